@@ -0,0 +1,194 @@
+package tags
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-catupiry/catu"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Returned when one model field is registered twice
+var ErrFieldAlreadyRegistered = errors.New("taxonomy field already registered for this model")
+
+// Register one taxonomy field, call it in your plugin Init:
+//
+//	p := app.GetPlugin("taxonomy").(*tags.Plugin)
+//	p.RegisterField(tags.NewTagFieldConfiguration("Tags", "content", "tags"))
+func (r *Plugin) RegisterField(f FieldConfigurationInterface) error {
+	if r.GetField(f.GetModelName(), f.GetFieldName()) != nil {
+		return errors.Wrap(ErrFieldAlreadyRegistered, f.GetModelName()+"."+f.GetFieldName())
+	}
+
+	r.Fields = append(r.Fields, f)
+
+	return nil
+}
+
+// Register one model with taxonomy struct tags, call it in your plugin Init:
+//
+//	p.RegisterModel(&ContentModel{})
+//
+// The model fields are registered and its terms are loaded and saved with gorm callbacks, see TaxonomyStructField
+func (r *Plugin) RegisterModel(model interface{}) {
+	r.Models = append(r.Models, model)
+}
+
+// SetupModels - Register the gorm taxonomy callbacks and the registered models fields
+func (r *Plugin) SetupModels(app catu.App) error {
+	err := RegisterTaxonomyCallbacks(app.GetDB())
+	if err != nil {
+		return err
+	}
+
+	for _, model := range r.Models {
+		fields, err := ParseTaxonomyFields(model)
+		if err != nil {
+			return errors.Wrap(err, "Plugin.SetupModels error on parse model taxonomy fields")
+		}
+
+		for i := range fields {
+			err = r.RegisterField(fields[i].Config)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Get one registered field, returns nil if not found
+func (r *Plugin) GetField(modelName, fieldName string) FieldConfigurationInterface {
+	for i := range r.Fields {
+		if r.Fields[i].GetModelName() == modelName && r.Fields[i].GetFieldName() == fieldName {
+			return r.Fields[i]
+		}
+	}
+
+	return nil
+}
+
+// Get all registered fields
+func (r *Plugin) GetFields() []FieldConfigurationInterface {
+	return r.Fields
+}
+
+// Get the registered fields of one model
+func (r *Plugin) GetModelFields(modelName string) []FieldConfigurationInterface {
+	fields := []FieldConfigurationInterface{}
+	for i := range r.Fields {
+		if r.Fields[i].GetModelName() == modelName {
+			fields = append(fields, r.Fields[i])
+		}
+	}
+
+	return fields
+}
+
+// Get the registered fields that use one vocabulary
+func (r *Plugin) GetVocabularyFields(vocabularyName string) []FieldConfigurationInterface {
+	fields := []FieldConfigurationInterface{}
+	for i := range r.Fields {
+		if r.Fields[i].GetVocabularyName() == vocabularyName {
+			fields = append(fields, r.Fields[i])
+		}
+	}
+
+	return fields
+}
+
+// Check if one registered field of the vocabulary only accepts lowercase term texts
+func (r *Plugin) IsVocabularyOnlyLowercase(vocabularyName string) bool {
+	for _, f := range r.GetVocabularyFields(vocabularyName) {
+		if FieldIsOnlyLowercase(f) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Remove the terms of all registered fields of one record. Records of models with one registered field are
+// cleared automatically after delete with gorm if the model name is the table name, see RegisterTaxonomyCallbacks
+func (r *Plugin) ClearRecord(modelName, modelId string) error {
+	return r.ClearRecordContext(context.Background(), modelName, modelId)
+}
+
+// ClearRecordContext - ClearRecord using ctx in the database queries
+func (r *Plugin) ClearRecordContext(ctx context.Context, modelName, modelId string) error {
+	return r.ClearRecordWithDB(catu.GetDefaultDatabaseConnection().WithContext(ctx), modelName, modelId)
+}
+
+// ClearRecordWithDB - ClearRecord using the db connection or transaction in the database queries
+func (r *Plugin) ClearRecordWithDB(db *gorm.DB, modelName, modelId string) error {
+	for _, f := range r.GetModelFields(modelName) {
+		err := FieldWithDB(f, db).ClearField(modelId)
+		if err != nil {
+			return errors.Wrap(err, "Plugin.ClearRecord error on clear field "+f.GetFieldName())
+		}
+	}
+
+	return nil
+}
+
+// FieldInfo - Registered field settings, used in the fields introspection endpoint
+type FieldInfo struct {
+	ModelName         string `json:"modelName"`
+	FieldName         string `json:"fieldName"`
+	VocabularyName    string `json:"vocabularyName"`
+	CanCreate         bool   `json:"canCreate"`
+	FormFieldMultiple bool   `json:"formFieldMultiple"`
+	OnlyLowercase     bool   `json:"onlyLowercase"`
+}
+
+func NewFieldInfo(f FieldConfigurationInterface) FieldInfo {
+	return FieldInfo{
+		ModelName:         f.GetModelName(),
+		FieldName:         f.GetFieldName(),
+		VocabularyName:    f.GetVocabularyName(),
+		CanCreate:         f.CanCreateTerm(),
+		FormFieldMultiple: f.IsFormFieldMultiple(),
+		OnlyLowercase:     FieldIsOnlyLowercase(f),
+	}
+}
+
+type FieldListJSONResponse struct {
+	catu.BaseListReponse
+	Records []FieldInfo `json:"field"`
+}
+
+// FieldsHandler - List registered fields, filter with the modelName and vocabularyName query params
+func (r *Plugin) FieldsHandler(c echo.Context) error {
+	modelName := c.QueryParam("modelName")
+	vocabularyName := c.QueryParam("vocabularyName")
+
+	records := []FieldInfo{}
+	for _, f := range r.GetFields() {
+		if modelName != "" && f.GetModelName() != modelName {
+			continue
+		}
+
+		if vocabularyName != "" && f.GetVocabularyName() != vocabularyName {
+			continue
+		}
+
+		records = append(records, NewFieldInfo(f))
+	}
+
+	resp := FieldListJSONResponse{
+		Records: records,
+	}
+
+	resp.Meta.Count = int64(len(records))
+
+	return c.JSON(http.StatusOK, &resp)
+}
+
+// Get the taxonomy plugin from the app, returns nil if it is not registered
+func GetPluginFromApp(app catu.App) *Plugin {
+	p, _ := app.GetPlugin("taxonomy").(*Plugin)
+	return p
+}
@@ -0,0 +1,82 @@
+package tags
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// Field implemented outside this package, without the optional field interfaces
+type externalFieldStub struct {
+	FieldConfigurationInterface
+}
+
+func TestFieldRegistry(t *testing.T) {
+	assert := assert.New(t)
+	app := GetAppInstance()
+	db := app.GetDB()
+	assert.Nil(RegisterTaxonomyCallbacks(db))
+
+	p := NewPlugin(&PluginCfgs{})
+	tags := NewTagFieldConfiguration("registrytags", "content_model_stubs", "tags")
+	category := NewCategoryFieldConfiguration("registrycategory", "content_model_stubs", "category")
+	external := &externalFieldStub{NewTagFieldConfiguration("registrytags", "external_stubs", "tags")}
+
+	assert.Nil(p.RegisterField(tags))
+	assert.Nil(p.RegisterField(category))
+	assert.Nil(p.RegisterField(external))
+	assert.ErrorIs(p.RegisterField(NewTagFieldConfiguration("other", "content_model_stubs", "tags")), ErrFieldAlreadyRegistered)
+
+	assert.Equal(tags, p.GetField("content_model_stubs", "tags"))
+	assert.Nil(p.GetField("content_model_stubs", "unknown"))
+	assert.Equal(2, len(p.GetModelFields("content_model_stubs")))
+	assert.Equal(2, len(p.GetVocabularyFields("registrytags")))
+
+	t.Run("Should use the optional field interfaces if implemented", func(t *testing.T) {
+		assert.True(FieldIsOnlyLowercase(tags))
+		assert.False(FieldIsOnlyLowercase(external))
+		assert.NotSame(tags, FieldWithDB(tags, db))
+		assert.Same(external, FieldWithDB(external, db))
+	})
+
+	t.Run("Should list the fields", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/v1/taxonomy-fields?vocabularyName=registrytags", nil)
+		rec := httptest.NewRecorder()
+		assert.Nil(p.FieldsHandler(echo.New().NewContext(req, rec)))
+
+		resp := struct {
+			Records []FieldInfo `json:"field"`
+		}{}
+		assert.Nil(json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Equal(2, len(resp.Records))
+		assert.Equal("content_model_stubs", resp.Records[0].ModelName)
+		assert.True(resp.Records[0].OnlyLowercase)
+		assert.Equal("external_stubs", resp.Records[1].ModelName)
+	})
+
+	t.Run("Should clear the registered fields after delete", func(t *testing.T) {
+		app.SetPlugin("taxonomy", p)
+		defer delete(app.GetPlugins(), "taxonomy")
+
+		record := GetContentModelStub()
+		assert.Nil(db.Create(&record).Error)
+		id := strconv.FormatUint(record.ID, 10)
+
+		assert.Nil(tags.Update(id, []string{"go", "gorm"}))
+		assert.Nil(db.Create(&TermModel{Text: "news", VocabularyName: "registrycategory"}).Error)
+		assert.Nil(category.Update(id, []string{"news"}))
+
+		var count int64
+		assert.Nil(db.Model(&ModelstermsModel{}).Where("modelName = ? AND modelId = ?", "content_model_stubs", id).Count(&count).Error)
+		assert.Equal(int64(3), count)
+
+		assert.Nil(db.Delete(&record).Error)
+
+		assert.Nil(db.Model(&ModelstermsModel{}).Where("modelName = ? AND modelId = ?", "content_model_stubs", id).Count(&count).Error)
+		assert.Equal(int64(0), count)
+	})
+}
@@ -0,0 +1,184 @@
+package tags
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/go-catupiry/catu"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// FacetTerm - One term in a facet with the number of result records using it
+type FacetTerm struct {
+	ID            uint64 `gorm:"column:id" json:"id"`
+	Text          string `gorm:"column:text" json:"text"`
+	Count         int64  `gorm:"column:count" json:"count"`
+	LinkPermanent string `gorm:"-" json:"linkPermanent"`
+}
+
+// Facet - Term counts of one vocabulary field
+type Facet struct {
+	VocabularyName string      `json:"vocabularyName"`
+	Field          string      `json:"field"`
+	Terms          []FacetTerm `json:"terms"`
+}
+
+type FacetOpts struct {
+	ModelName string
+	// Result set model ids, a []string, []uint64 or a *gorm.DB subquery selecting one id column.
+	// All model records are counted if nil
+	ModelIDs interface{}
+	// Only count these vocabularies and fields, all if empty
+	VocabularyNames []string
+	Fields          []string
+	// Only count records matching the tag expression
+	TermExpression *TermExpression
+	// Max terms per facet, the most used terms are selected, all if <= 0
+	Limit int
+}
+
+type facetTermCount struct {
+	VocabularyName string `gorm:"column:vocabularyName"`
+	Field          string `gorm:"column:field"`
+	ID             uint64 `gorm:"column:id"`
+	Text           string `gorm:"column:text"`
+	Count          int64  `gorm:"column:count"`
+}
+
+// Count the terms of a model result set grouped by vocabulary and field. Facets are sorted by vocabulary and
+// field and their terms by count
+func ModelstermsFacets(opts *FacetOpts, facets *[]Facet) error {
+	return ModelstermsFacetsContext(context.Background(), opts, facets)
+}
+
+// ModelstermsFacetsContext - ModelstermsFacets using ctx in the database queries
+func ModelstermsFacetsContext(ctx context.Context, opts *FacetOpts, facets *[]Facet) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	query := db.Table("modelsterms AS M").
+		Select("M.vocabularyName AS vocabularyName, M.field AS field, T.id AS id, T.text AS text, "+
+			"COUNT(DISTINCT M.modelId) AS count").
+		Joins("INNER JOIN terms AS T ON T.id = M.termId").
+		Where("M.modelName = ?", opts.ModelName)
+
+	if opts.ModelIDs != nil {
+		if subquery, ok := opts.ModelIDs.(*gorm.DB); ok {
+			// the subquery may come from other connection, run it in this one to keep ctx
+			query = query.Where("M.modelId IN (?)", subquery.WithContext(ctx))
+		} else {
+			query = query.Where("M.modelId IN ?", opts.ModelIDs)
+		}
+	}
+
+	if opts.TermExpression != nil {
+		query = opts.TermExpression.whereModelsterms(query, "M", "")
+	}
+
+	if len(opts.VocabularyNames) > 0 {
+		query = query.Where("M.vocabularyName IN ?", opts.VocabularyNames)
+	}
+
+	if len(opts.Fields) > 0 {
+		query = query.Where("M.field IN ?", opts.Fields)
+	}
+
+	counts := []facetTermCount{}
+	err := query.
+		Group("M.vocabularyName").
+		Group("M.field").
+		Group("T.id").
+		Group("T.text").
+		Order("vocabularyName ASC").
+		Order("field ASC").
+		Order("count DESC").
+		Order("text ASC").
+		Scan(&counts).Error
+	if err != nil {
+		return errors.Wrap(err, "ModelstermsFacets error on count terms")
+	}
+
+	for _, c := range counts {
+		n := len(*facets)
+		if n == 0 || (*facets)[n-1].VocabularyName != c.VocabularyName || (*facets)[n-1].Field != c.Field {
+			*facets = append(*facets, Facet{VocabularyName: c.VocabularyName, Field: c.Field})
+			n++
+		}
+
+		facet := &(*facets)[n-1]
+		if opts.Limit > 0 && len(facet.Terms) >= opts.Limit {
+			continue
+		}
+
+		t := TermModel{ID: c.ID, VocabularyName: c.VocabularyName}
+		t.LoadPath()
+
+		facet.Terms = append(facet.Terms, FacetTerm{
+			ID:            c.ID,
+			Text:          c.Text,
+			Count:         c.Count,
+			LinkPermanent: t.LinkPermanent,
+		})
+	}
+
+	return nil
+}
+
+type FacetsJSONResponse struct {
+	catu.BaseListReponse
+	Records []Facet `json:"facet"`
+}
+
+// FacetsHandler - Return the term facets of :modelName records.
+// Query params: modelId, vocabularyName and field, repeated or comma separated, tagq and limit (terms per facet)
+func (r *Plugin) FacetsHandler(c echo.Context) error {
+	query := c.QueryParams()
+
+	expr, err := TermExpressionFromReq(c)
+	if err != nil {
+		return err
+	}
+
+	opts := FacetOpts{
+		ModelName:       c.Param("modelName"),
+		VocabularyNames: splitQueryValues(query["vocabularyName"]),
+		Fields:          splitQueryValues(query["field"]),
+		TermExpression:  expr,
+		Limit:           catu.GetQueryIntFromReq("limit", c),
+	}
+
+	if ids := splitQueryValues(query["modelId"]); len(ids) > 0 {
+		opts.ModelIDs = ids
+	}
+
+	facets := []Facet{}
+	err = ModelstermsFacetsContext(c.Request().Context(), &opts, &facets)
+	if err != nil {
+		return errors.Wrap(err, "Plugin.FacetsHandler error on count facets")
+	}
+
+	resp := FacetsJSONResponse{
+		Records: facets,
+	}
+
+	resp.Meta.Count = int64(len(facets))
+
+	return c.JSON(http.StatusOK, &resp)
+}
+
+// Split repeated and comma separated query param values, ex: ?a=1,2&a=3 -> [1 2 3]
+func splitQueryValues(values []string) []string {
+	var result []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			part = strings.TrimSpace(part)
+			if part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+
+	return result
+}
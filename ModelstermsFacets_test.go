@@ -0,0 +1,65 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelstermsFacets(t *testing.T) {
+	assert := assert.New(t)
+	app := GetAppInstance()
+
+	tags := NewTagFieldConfiguration("facettags", "facetpost", "tags")
+	category := NewTagFieldConfiguration("facetcategory", "facetpost", "category")
+	assert.Nil(tags.Update("1", []string{"go", "sql"}))
+	assert.Nil(tags.Update("2", []string{"go"}))
+	assert.Nil(tags.Update("3", []string{"rust"}))
+	assert.Nil(category.Update("1", []string{"tech"}))
+	assert.Nil(category.Update("2", []string{"tech"}))
+	assert.Nil(category.Update("3", []string{"health"}))
+
+	db := app.GetDB()
+
+	facets := []Facet{}
+	err := ModelstermsFacets(&FacetOpts{
+		ModelName: "facetpost",
+		ModelIDs: db.Model(&ModelstermsModel{}).
+			Select("modelId").
+			Where("modelName = ? AND modelId IN ?", "facetpost", []string{"1", "2"}),
+	}, &facets)
+	assert.Nil(err)
+	assert.Equal(2, len(facets))
+	assert.Equal("facetcategory", facets[0].VocabularyName)
+	assert.Equal(1, len(facets[0].Terms))
+	assert.Equal("tech", facets[0].Terms[0].Text)
+	assert.Equal(int64(2), facets[0].Terms[0].Count)
+	assert.Equal("facettags", facets[1].VocabularyName)
+	assert.Equal("go", facets[1].Terms[0].Text)
+	assert.Equal(int64(2), facets[1].Terms[0].Count)
+	assert.Equal("sql", facets[1].Terms[1].Text)
+
+	facets = []Facet{}
+	err = ModelstermsFacets(&FacetOpts{
+		ModelName:       "facetpost",
+		ModelIDs:        []string{"3"},
+		VocabularyNames: []string{"facetcategory"},
+	}, &facets)
+	assert.Nil(err)
+	assert.Equal(1, len(facets))
+	assert.Equal("health", facets[0].Terms[0].Text)
+
+	expr, err := ParseTermExpression("facettags:sql OR facetcategory:health")
+	assert.Nil(err)
+
+	facets = []Facet{}
+	err = ModelstermsFacets(&FacetOpts{
+		ModelName:       "facetpost",
+		VocabularyNames: []string{"facetcategory"},
+		TermExpression:  expr,
+	}, &facets)
+	assert.Nil(err)
+	assert.Equal(1, len(facets))
+	assert.Equal(2, len(facets[0].Terms))
+	assert.Equal(int64(1), facets[0].Terms[0].Count)
+}
@@ -2,6 +2,7 @@ package tags
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -62,24 +63,43 @@ func (r *ModelstermsModel) ToJSON() string {
 
 // Save - Create if is new or update
 func (m *ModelstermsModel) Save() error {
-	var err error
-	db := catu.GetDefaultDatabaseConnection()
+	return m.SaveContext(context.Background())
+}
 
-	if m.ID == 0 {
-		// create ....
-		err = db.Create(&m).Error
-		if err != nil {
-			return err
+// SaveContext - Save using ctx in the database queries
+func (m *ModelstermsModel) SaveContext(ctx context.Context) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		termIds := []uint64{}
+
+		if m.ID == 0 {
+			// create ....
+			err := tx.Create(&m).Error
+			if err != nil {
+				return err
+			}
+		} else {
+			// the old term usage also changes if the term is replaced
+			old, err := modelstermsTermIDs(tx.Where("id = ?", m.ID))
+			if err != nil {
+				return err
+			}
+			termIds = append(termIds, old...)
+
+			// update ...
+			err = tx.Save(&m).Error
+			if err != nil {
+				return err
+			}
 		}
-	} else {
-		// update ...
-		err = db.Save(&m).Error
-		if err != nil {
-			return err
+
+		if m.TermID != nil {
+			termIds = append(termIds, *m.TermID)
 		}
-	}
 
-	return nil
+		return termUpdateUsage(tx, termIds)
+	})
 }
 
 func (r *ModelstermsModel) RenderRelatedRecord(ctx *catu.RequestContext, app catu.App) (bytes.Buffer, error) {
@@ -88,8 +108,25 @@ func (r *ModelstermsModel) RenderRelatedRecord(ctx *catu.RequestContext, app cat
 }
 
 func (r *ModelstermsModel) Delete() error {
-	db := catu.GetDefaultDatabaseConnection()
-	return db.Unscoped().Delete(&r).Error
+	return r.DeleteContext(context.Background())
+}
+
+// DeleteContext - Delete using ctx in the database queries
+func (r *ModelstermsModel) DeleteContext(ctx context.Context) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Delete(&r).Error
+		if err != nil {
+			return err
+		}
+
+		if r.TermID == nil {
+			return nil
+		}
+
+		return termUpdateUsage(tx, []uint64{*r.TermID})
+	})
 }
 
 type ModelstermQueryOpts struct {
@@ -99,20 +136,34 @@ type ModelstermQueryOpts struct {
 	Offset  int
 	C       echo.Context
 	IsHTML  bool
+	// Term id to filter, defaults to the :id route param
+	TermID string
+	// Only list associations of these fields, all fields if empty
+	Fields []FieldConfigurationInterface
+	// Only list associations of records matching the tag expression, unprefixed terms use the vocabulary param
+	TermExpression *TermExpression
 }
 
 func ModelstermQueryAndCountReq(opts *ModelstermQueryOpts) error {
-	db := catu.GetDefaultDatabaseConnection()
+	return ModelstermQueryAndCountReqContext(context.Background(), opts)
+}
+
+// ModelstermQueryAndCountReqContext - ModelstermQueryAndCountReq using ctx in the database queries
+func ModelstermQueryAndCountReqContext(ctx context.Context, opts *ModelstermQueryOpts) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
 
 	c := opts.C
 	vocabularyName := c.Param("vocabulary")
-	termId := c.Param("id")
+	termId := opts.TermID
+	if termId == "" {
+		termId = c.Param("id")
+	}
 
 	query := db
 
-	ctx := c.(*catu.RequestContext)
+	rctx := c.(*catu.RequestContext)
 
-	queryI, err := ctx.Query.SetDatabaseQueryForModel(query, &VocabularyModel{})
+	queryI, err := rctx.Query.SetDatabaseQueryForModel(query, &VocabularyModel{})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": fmt.Sprintf("%+v\n", err),
@@ -121,7 +172,16 @@ func ModelstermQueryAndCountReq(opts *ModelstermQueryOpts) error {
 	query = queryI.(*gorm.DB)
 
 	if termId != "" {
-		query = query.Where("termId = ?", termId)
+		query, err = modelstermsWhereTermSubtree(db, query, termId, opts.Fields)
+		if err != nil {
+			return err
+		}
+	}
+
+	query = modelstermsWhereFields(query, opts.Fields)
+
+	if opts.TermExpression != nil {
+		query = opts.TermExpression.whereModelsterms(query, "modelsterms", vocabularyName)
 	}
 
 	if vocabularyName != "" {
@@ -149,20 +209,30 @@ func ModelstermQueryAndCountReq(opts *ModelstermQueryOpts) error {
 		return r.Error
 	}
 
-	return ModelstermsCountReq(opts)
+	return ModelstermsCountReqContext(ctx, opts)
 }
 
 func ModelstermsCountReq(opts *ModelstermQueryOpts) error {
-	db := catu.GetDefaultDatabaseConnection()
+	return ModelstermsCountReqContext(context.Background(), opts)
+}
+
+// ModelstermsCountReqContext - ModelstermsCountReq using ctx in the database queries
+func ModelstermsCountReqContext(ctx context.Context, opts *ModelstermQueryOpts) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
 
 	c := opts.C
+	vocabularyName := c.Param("vocabulary")
+	termId := opts.TermID
+	if termId == "" {
+		termId = c.Param("id")
+	}
 
-	ctx := c.(*catu.RequestContext)
+	rctx := c.(*catu.RequestContext)
 
 	// Count ...
 	queryCount := db
 
-	queryICount, err := ctx.Query.SetDatabaseQueryForModel(queryCount, &VocabularyModel{})
+	queryICount, err := rctx.Query.SetDatabaseQueryForModel(queryCount, &VocabularyModel{})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": fmt.Sprintf("%+v\n", err),
@@ -170,7 +240,54 @@ func ModelstermsCountReq(opts *ModelstermQueryOpts) error {
 	}
 	queryCount = queryICount.(*gorm.DB)
 
+	if termId != "" {
+		queryCount, err = modelstermsWhereTermSubtree(db, queryCount, termId, opts.Fields)
+		if err != nil {
+			return err
+		}
+	}
+
+	queryCount = modelstermsWhereFields(queryCount, opts.Fields)
+
+	if opts.TermExpression != nil {
+		queryCount = opts.TermExpression.whereModelsterms(queryCount, "modelsterms", vocabularyName)
+	}
+
+	if vocabularyName != "" {
+		queryCount = queryCount.Where("vocabularyName = ?", vocabularyName)
+	}
+
 	return queryCount.
 		Table("modelsterms").
 		Count(opts.Count).Error
 }
+
+// Filter associations with the term or any of its descendants, returning only one association for each record
+func modelstermsWhereTermSubtree(db, query *gorm.DB, termId string, fields []FieldConfigurationInterface) (*gorm.DB, error) {
+	termIds, err := TermFindSubtreeIDsContext(db.Statement.Context, termId)
+	if err != nil {
+		return nil, err
+	}
+
+	firstAssocs := modelstermsWhereFields(db.Model(&ModelstermsModel{}), fields).
+		Select("MIN(id)").
+		Where("termId IN ?", termIds).
+		Group("modelName").
+		Group("modelId")
+
+	return query.Where("id IN (?)", firstAssocs), nil
+}
+
+// Filter associations by model name and field of the fields list, skipped if the list is empty
+func modelstermsWhereFields(query *gorm.DB, fields []FieldConfigurationInterface) *gorm.DB {
+	if len(fields) == 0 {
+		return query
+	}
+
+	where := catu.GetDefaultDatabaseConnection()
+	for i := range fields {
+		where = where.Or("modelName = ? AND field = ?", fields[i].GetModelName(), fields[i].GetFieldName())
+	}
+
+	return query.Where(where)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/go-catupiry/catu"
 	"github.com/gookit/event"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,7 +16,14 @@ type Plugin struct {
 	VocabularyController *VocabularyController
 	TermController       *TermController
 
+	// Taxonomy fields registered by other plugins, see RegisterField
+	Fields []FieldConfigurationInterface
+	// Models with taxonomy struct tags, see RegisterModel
+	Models []interface{}
+
 	RenderRelatedRecord func(mt *ModelstermsModel, ctx *catu.RequestContext) (bytes.Buffer, error)
+	// Build the term feed item of one associated record, return nil to skip the record
+	RenderFeedItem func(mt *ModelstermsModel, ctx *catu.RequestContext) (*FeedItem, error)
 }
 
 func (r *Plugin) GetName() string {
@@ -28,13 +36,64 @@ func (r *Plugin) Init(app catu.App) error {
 	r.VocabularyController = NewVocabularyController(&VocabularyControllerCfg{App: app})
 	r.TermController = NewTermController(&TermControllerCfg{App: app})
 
+	// the database connection is only available after the configuration event
+	app.GetEvents().On("bindMiddlewares", event.ListenerFunc(func(e event.Event) error {
+		err := TermSearchSetup(app.GetDB())
+		if err != nil {
+			return err
+		}
+
+		return r.SetupModels(app)
+	}), event.Normal)
+
+	// low priority to run after the app and plugins migrations
+	app.GetEvents().On("migrate", event.ListenerFunc(func(e event.Event) error {
+		return r.Migrate(app)
+	}), event.Low)
+
 	app.GetEvents().On("bindRoutes", event.ListenerFunc(func(e event.Event) error {
 		return r.BindRoutes(app)
 	}), event.Normal)
 
+	app.GetEvents().On("setTemplateFunctions", event.ListenerFunc(func(e event.Event) error {
+		return r.SetTemplateFuncMap(app)
+	}), event.Normal)
+
 	return nil
 }
 
+// Migrate - Create or update the taxonomy tables, setup the search index and fill the term columns of the terms saved
+// before its features, runs after the app migrations with app.Migrate()
+func (r *Plugin) Migrate(app catu.App) error {
+	logrus.Debug(r.GetName() + " Migrate")
+
+	err := app.GetDB().AutoMigrate(
+		&VocabularyModel{},
+		&TermModel{},
+		&ModelstermsModel{},
+		&TermRedirectModel{},
+		&TermAliasModel{},
+		&TermSlugRedirectModel{},
+		&TermTranslationModel{},
+	)
+	if err != nil {
+		return errors.Wrap(err, "Plugin.Migrate error on migrate tables")
+	}
+
+	// the setup in bindMiddlewares runs before the app migrations, ex: in one new database without the terms table
+	err = TermSearchSetup(app.GetDB())
+	if err != nil {
+		return err
+	}
+
+	err = TermEnsureSlugs()
+	if err != nil {
+		return err
+	}
+
+	return TermEnsureSearchTexts()
+}
+
 func (r *Plugin) BindRoutes(app catu.App) error {
 	logrus.Debug(r.GetName() + " On BindRoutes")
 
@@ -43,34 +102,84 @@ func (r *Plugin) BindRoutes(app catu.App) error {
 
 	mainRouter := app.GetRouterGroup("main")
 	mainRouter.GET("api/v1/term-texts", termCTL.TermTexts)
+	mainRouter.GET("api/v1/taxonomy-fields", r.FieldsHandler)
+	mainRouter.GET("api/v1/related-records/:modelName/:modelId", r.RelatedRecordsHandler)
+	mainRouter.GET("api/v1/facets/:modelName", r.FacetsHandler)
+	mainRouter.GET("sitemap-taxonomy.xml", r.SitemapIndexHandler)
+	mainRouter.GET("sitemap-taxonomy/:page", r.SitemapHandler)
 
 	routerApi := app.SetRouterGroup("vocabulary-api", "/api/vocabulary")
 
 	app.SetResource("vocabulary", vocabularyCTL, routerApi)
+	routerApi.GET("/:vocabulary/tag-cloud", termCTL.TagClound)
+	routerApi.GET("/:vocabulary/export", vocabularyCTL.Export)
+	routerApi.POST("/:vocabulary/import", vocabularyCTL.Import)
 
 	routerVocTermApi := app.SetRouterGroup("vocabulary-term-api", "/api/vocabulary/:vocabulary/term")
+	routerVocTermApi.GET("/tree", termCTL.Tree)
+	routerVocTermApi.GET("/autocomplete", termCTL.Autocomplete)
+	routerVocTermApi.GET("/search", termCTL.Search)
+	routerVocTermApi.GET("/:id/related", termCTL.Related)
+	routerVocTermApi.POST("/:id/merge", termCTL.Merge)
+	routerVocTermApi.POST("/recount-usage", termCTL.RecountUsage)
 	app.SetResource("vocabulary-term", termCTL, routerVocTermApi)
 
-	mainRouter.GET("vocabulary/:vocabulary/term/:id", termCTL.FindOnePageHandler)
+	mainRouter.GET("vocabulary", vocabularyCTL.FindAllPageHandler)
+	mainRouter.GET("vocabulary/:vocabulary", termCTL.FindAllPageHandler)
+	mainRouter.GET("vocabulary/:vocabulary/term/:slug", termCTL.FindOnePageHandler)
+	mainRouter.GET("vocabulary/:vocabulary/term/:slug/feed", termCTL.Feed)
+	mainRouter.GET(":locale/vocabulary/:vocabulary", termCTL.FindAllPageHandler)
+	mainRouter.GET(":locale/vocabulary/:vocabulary/term/:slug", termCTL.FindOnePageHandler)
 
 	return nil
 }
 
 func (r *Plugin) SetTemplateFuncMap(app catu.App) error {
+	app.SetTemplateFunction("tagCloud", tagCloudTemplateFunc)
 	return nil
 }
 
+// Template helper, usage: {{ range $t := (tagCloud "Tags" 30) }} ... {{ end }}
+func tagCloudTemplateFunc(vocabularyName string, limit int) []TagCloudItem {
+	records := []TagCloudItem{}
+	err := TagCloudFind(&TagCloudOpts{
+		VocabularyName: vocabularyName,
+		Limit:          limit,
+	}, &records)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"vocabularyName": vocabularyName,
+			"error":          err,
+		}).Error("tagCloud template function error on find tag cloud")
+	}
+
+	return records
+}
+
 type PluginCfgs struct {
 	RenderRelatedRecord func(mt *ModelstermsModel, ctx *catu.RequestContext) (bytes.Buffer, error)
+	RenderFeedItem      func(mt *ModelstermsModel, ctx *catu.RequestContext) (*FeedItem, error)
 }
 
 func NewPlugin(cfg *PluginCfgs) *Plugin {
-	p := Plugin{Name: "taxonomy", RenderRelatedRecord: cfg.RenderRelatedRecord}
+	p := Plugin{
+		Name:                "taxonomy",
+		RenderRelatedRecord: cfg.RenderRelatedRecord,
+		RenderFeedItem:      cfg.RenderFeedItem,
+		Fields:              []FieldConfigurationInterface{},
+		Models:              []interface{}{},
+	}
 
 	if p.RenderRelatedRecord == nil {
 		p.RenderRelatedRecord = func(mt *ModelstermsModel, ctx *catu.RequestContext) (bytes.Buffer, error) {
 			return bytes.Buffer{}, nil
 		}
 	}
+
+	if p.RenderFeedItem == nil {
+		p.RenderFeedItem = func(mt *ModelstermsModel, ctx *catu.RequestContext) (*FeedItem, error) {
+			return nil, nil
+		}
+	}
 	return &p
 }
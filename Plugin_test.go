@@ -0,0 +1,41 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestPluginMigrate(t *testing.T) {
+	assert := assert.New(t)
+	app := GetAppInstance()
+
+	// one new database without the taxonomy tables
+	db, err := gorm.Open(sqlite.Open("file:pluginmigrate?mode=memory&cache=shared"), &gorm.Config{})
+	assert.Nil(err)
+
+	appDB := app.GetDB()
+	assert.Nil(app.SetDB(db))
+	defer app.SetDB(appDB)
+
+	// terms saved before the slug column
+	assert.Nil(db.Exec("CREATE TABLE `terms` (`id` integer PRIMARY KEY, `text` varchar(255) NOT NULL, " +
+		"`vocabularyName` varchar(255) NOT NULL, `createdAt` datetime NOT NULL, `updatedAt` datetime NOT NULL)").Error)
+	assert.Nil(db.Exec("INSERT INTO terms (id, text, vocabularyName, createdAt, updatedAt) VALUES " +
+		"(1, 'Go Lang', 'Tags', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP), (2, '2024', 'Tags', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)").Error)
+
+	assert.Nil(NewPlugin(&PluginCfgs{}).Migrate(app))
+
+	for _, table := range []string{"vocabularies", "terms", "modelsterms", "term_redirects", "term_aliases", "term_slug_redirects", "term_translations"} {
+		assert.True(db.Migrator().HasTable(table), table)
+	}
+
+	terms := []TermModel{}
+	assert.Nil(db.Order("id ASC").Find(&terms).Error)
+	assert.Equal(2, len(terms))
+	assert.Equal("go-lang", terms[0].Slug)
+	assert.Equal("go lang", terms[0].SearchText)
+	assert.Equal("term-2024", terms[1].Slug)
+}
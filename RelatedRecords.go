@@ -0,0 +1,144 @@
+package tags
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-catupiry/catu"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Max related records returned in one request
+const RelatedRecordsMaxLimit = 50
+
+// RelatedRecord - One record sharing terms with the source record
+type RelatedRecord struct {
+	ModelName string `gorm:"column:modelName" json:"modelName"`
+	ModelID   uint64 `gorm:"column:modelId" json:"modelId"`
+	// Jaccard index of the two records terms: shared / (source terms + record terms - shared)
+	Score float64 `gorm:"column:score" json:"score"`
+	// Number of shared terms
+	Shared int64 `gorm:"column:shared" json:"shared"`
+}
+
+type RelatedRecordsOpts struct {
+	// Source record
+	ModelName string
+	ModelID   string
+	// Only compare terms of this vocabulary and field, all if empty
+	VocabularyName string
+	Field          string
+	// Only return records of this model, all models if empty
+	RelatedModelName string
+	// Defaults to 10, max RelatedRecordsMaxLimit
+	Limit int
+}
+
+// Find the records sharing the most terms with the source record, sorted by the Jaccard index
+func ModelstermsFindRelated(opts *RelatedRecordsOpts, records *[]RelatedRecord) error {
+	return ModelstermsFindRelatedContext(context.Background(), opts, records)
+}
+
+// ModelstermsFindRelatedContext - ModelstermsFindRelated using ctx in the database queries
+func ModelstermsFindRelatedContext(ctx context.Context, opts *RelatedRecordsOpts, records *[]RelatedRecord) error {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > RelatedRecordsMaxLimit {
+		limit = RelatedRecordsMaxLimit
+	}
+
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	// assocs compared in the source and related records
+	assocs := func() *gorm.DB {
+		query := db.Model(&ModelstermsModel{}).Where("termId IS NOT NULL")
+		if opts.VocabularyName != "" {
+			query = query.Where("vocabularyName = ?", opts.VocabularyName)
+		}
+		if opts.Field != "" {
+			query = query.Where("field = ?", opts.Field)
+		}
+		return query
+	}
+
+	sourceTermIDs := assocs().
+		Distinct("termId").
+		Where("modelName = ? AND modelId = ?", opts.ModelName, opts.ModelID)
+
+	var sourceCount int64
+	err := db.Table("(?) AS S", sourceTermIDs).Count(&sourceCount).Error
+	if err != nil {
+		return errors.Wrap(err, "ModelstermsFindRelated error on count source terms")
+	}
+
+	if sourceCount == 0 {
+		return nil
+	}
+
+	candidates := assocs().
+		Select("modelName, modelId, COUNT(DISTINCT termId) AS shared").
+		Where("termId IN (?)", sourceTermIDs).
+		Where("NOT (modelName = ? AND modelId = ?)", opts.ModelName, opts.ModelID).
+		Group("modelName").
+		Group("modelId")
+
+	if opts.RelatedModelName != "" {
+		candidates = candidates.Where("modelName = ?", opts.RelatedModelName)
+	}
+
+	totals := assocs().
+		Select("modelName, modelId, COUNT(DISTINCT termId) AS total").
+		Where("modelId IN (?)", assocs().Select("modelId").Where("termId IN (?)", sourceTermIDs)).
+		Group("modelName").
+		Group("modelId")
+
+	err = db.Table("(?) AS C", candidates).
+		Select("C.modelName AS modelName, C.modelId AS modelId, C.shared AS shared, "+
+			"C.shared * 1.0 / (? + T.total - C.shared) AS score", sourceCount).
+		Joins("INNER JOIN (?) AS T ON T.modelName = C.modelName AND T.modelId = C.modelId", totals).
+		Order("score DESC").
+		Order("shared DESC").
+		Order("C.modelId DESC").
+		Limit(limit).
+		Scan(records).Error
+	if err != nil {
+		return errors.Wrap(err, "ModelstermsFindRelated error on find related records")
+	}
+
+	return nil
+}
+
+type RelatedRecordsJSONResponse struct {
+	catu.BaseListReponse
+	Records []RelatedRecord `json:"relatedRecord"`
+}
+
+// RelatedRecordsHandler - List records sharing terms with the :modelName and :modelId record.
+// Query params: vocabularyName, field, relatedModelName and limit
+func (r *Plugin) RelatedRecordsHandler(c echo.Context) error {
+	records := []RelatedRecord{}
+
+	err := ModelstermsFindRelatedContext(c.Request().Context(), &RelatedRecordsOpts{
+		ModelName:        c.Param("modelName"),
+		ModelID:          c.Param("modelId"),
+		VocabularyName:   c.QueryParam("vocabularyName"),
+		Field:            c.QueryParam("field"),
+		RelatedModelName: c.QueryParam("relatedModelName"),
+		Limit:            catu.GetQueryIntFromReq("limit", c),
+	}, &records)
+	if err != nil {
+		return errors.Wrap(err, "Plugin.RelatedRecordsHandler error on find related records")
+	}
+
+	resp := RelatedRecordsJSONResponse{
+		Records: records,
+	}
+
+	resp.Meta.Count = int64(len(records))
+
+	return c.JSON(http.StatusOK, &resp)
+}
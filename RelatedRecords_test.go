@@ -0,0 +1,32 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelstermsFindRelated(t *testing.T) {
+	assert := assert.New(t)
+	GetAppInstance()
+
+	cfg := NewTagFieldConfiguration("related", "article", "tags")
+	assert.Nil(cfg.Update("1", []string{"go", "sql", "orm"}))
+	assert.Nil(cfg.Update("2", []string{"go", "sql", "orm", "web"}))
+	assert.Nil(cfg.Update("3", []string{"go", "rust", "c", "zig"}))
+	assert.Nil(cfg.Update("4", []string{"python"}))
+
+	records := []RelatedRecord{}
+	err := ModelstermsFindRelated(&RelatedRecordsOpts{
+		ModelName:      "article",
+		ModelID:        "1",
+		VocabularyName: "related",
+	}, &records)
+	assert.Nil(err)
+	assert.Equal(2, len(records))
+	assert.Equal(uint64(2), records[0].ModelID)
+	assert.Equal(int64(3), records[0].Shared)
+	assert.InDelta(0.75, records[0].Score, 0.001)
+	assert.Equal(uint64(3), records[1].ModelID)
+	assert.InDelta(1.0/6.0, records[1].Score, 0.001)
+}
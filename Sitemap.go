@@ -0,0 +1,267 @@
+package tags
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-catupiry/catu"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Max URLs in one sitemap file, from the sitemaps protocol. A var to allow smaller sitemaps
+var SitemapMaxURLs = 50000
+
+const sitemapXMLNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// SitemapURL - One sitemap url entry
+type SitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []SitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	Sitemaps []SitemapURL `xml:"sitemap"`
+}
+
+// Aggregated dates are returned as text by some databases, like sqlite
+type sitemapTime struct {
+	Time time.Time
+}
+
+var sitemapTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func (t sitemapTime) Value() (driver.Value, error) {
+	return t.Time, nil
+}
+
+func (t *sitemapTime) Scan(value interface{}) error {
+	var text string
+
+	switch v := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	case time.Time:
+		t.Time = v
+		return nil
+	case []byte:
+		text = string(v)
+	case string:
+		text = v
+	default:
+		return errors.Errorf("sitemapTime: unsupported value type %T", value)
+	}
+
+	text = strings.TrimSuffix(text, "Z")
+	for _, layout := range sitemapTimeLayouts {
+		parsed, err := time.Parse(layout, text)
+		if err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+
+	return errors.Errorf("sitemapTime: invalid time %q", text)
+}
+
+type sitemapRow struct {
+	ID              uint64      `gorm:"column:id"`
+	Slug            string      `gorm:"column:slug"`
+	VocabularyName  string      `gorm:"column:vocabularyName"`
+	TermUpdatedAt   sitemapTime `gorm:"column:termUpdatedAt"`
+	AssocsUpdatedAt sitemapTime `gorm:"column:assocsUpdatedAt"`
+}
+
+// Newest term or association update
+func (r *sitemapRow) lastMod() string {
+	lastMod := r.TermUpdatedAt.Time
+	if r.AssocsUpdatedAt.Time.After(lastMod) {
+		lastMod = r.AssocsUpdatedAt.Time
+	}
+
+	if lastMod.IsZero() {
+		return ""
+	}
+
+	return lastMod.UTC().Format(time.RFC3339)
+}
+
+// Terms listed in sitemaps, without the terms and vocabularies with NoIndex
+func sitemapTermsQuery(db *gorm.DB) *gorm.DB {
+	return db.Table("terms AS T").
+		Joins("LEFT JOIN modelsterms AS M ON M.termId = T.id").
+		Where("T.noIndex = ?", false).
+		Where("T.vocabularyName NOT IN (?)", db.Model(&VocabularyModel{}).Select("name").Where("noIndex = ? AND name IS NOT NULL", true))
+}
+
+// Count the sitemap URLs, one for each vocabulary with terms and one for each term
+func SitemapCount() (int64, error) {
+	return SitemapCountContext(context.Background())
+}
+
+// SitemapCountContext - SitemapCount using ctx in the database queries
+func SitemapCountContext(ctx context.Context) (int64, error) {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	var vocabularies, terms int64
+
+	err := sitemapTermsQuery(db).Distinct("T.vocabularyName").Count(&vocabularies).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "SitemapCount error on count vocabularies")
+	}
+
+	err = sitemapTermsQuery(db).Distinct("T.id").Count(&terms).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "SitemapCount error on count terms")
+	}
+
+	return vocabularies + terms, nil
+}
+
+// Find the URLs of one sitemap page, starting at 1. Vocabulary pages come first and then the term pages
+// sorted by id, lastmod is the newest term or association update
+func SitemapFindURLs(page int, urls *[]SitemapURL) error {
+	return SitemapFindURLsContext(context.Background(), page, urls)
+}
+
+// SitemapFindURLsContext - SitemapFindURLs using ctx in the database queries
+func SitemapFindURLsContext(ctx context.Context, page int, urls *[]SitemapURL) error {
+	if page < 1 {
+		return nil
+	}
+
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+	origin := catu.GetConfiguration().Get("APP_ORIGIN")
+
+	offset := (page - 1) * SitemapMaxURLs
+	limit := SitemapMaxURLs
+
+	vocabularies := []sitemapRow{}
+	err := sitemapTermsQuery(db).
+		Select("T.vocabularyName AS vocabularyName, MAX(T.updatedAt) AS termUpdatedAt, MAX(M.updatedAt) AS assocsUpdatedAt").
+		Group("T.vocabularyName").
+		Order("T.vocabularyName ASC").
+		Scan(&vocabularies).Error
+	if err != nil {
+		return errors.Wrap(err, "SitemapFindURLs error on find vocabularies")
+	}
+
+	for i := offset; i < len(vocabularies) && limit > 0; i++ {
+		v := VocabularyModel{Name: vocabularies[i].VocabularyName}
+		*urls = append(*urls, SitemapURL{
+			Loc:     origin + v.GetPath(),
+			LastMod: vocabularies[i].lastMod(),
+		})
+		limit--
+	}
+
+	if limit == 0 {
+		return nil
+	}
+
+	termsOffset := offset - len(vocabularies)
+	if termsOffset < 0 {
+		termsOffset = 0
+	}
+
+	terms := []sitemapRow{}
+	err = sitemapTermsQuery(db).
+		Select("T.id AS id, T.slug AS slug, T.vocabularyName AS vocabularyName, " +
+			"T.updatedAt AS termUpdatedAt, MAX(M.updatedAt) AS assocsUpdatedAt").
+		Group("T.id").
+		Group("T.slug").
+		Group("T.vocabularyName").
+		Group("T.updatedAt").
+		Order("T.id ASC").
+		Limit(limit).
+		Offset(termsOffset).
+		Scan(&terms).Error
+	if err != nil {
+		return errors.Wrap(err, "SitemapFindURLs error on find terms")
+	}
+
+	for i := range terms {
+		t := TermModel{ID: terms[i].ID, Slug: terms[i].Slug, VocabularyName: terms[i].VocabularyName}
+		*urls = append(*urls, SitemapURL{
+			Loc:     origin + t.GetPath(),
+			LastMod: terms[i].lastMod(),
+		})
+	}
+
+	return nil
+}
+
+// SitemapIndexHandler - Sitemap index with one sitemap for each SitemapMaxURLs taxonomy URLs
+func (r *Plugin) SitemapIndexHandler(c echo.Context) error {
+	count, err := SitemapCountContext(c.Request().Context())
+	if err != nil {
+		return errors.Wrap(err, "Plugin.SitemapIndexHandler error on count urls")
+	}
+
+	pages := int((count + int64(SitemapMaxURLs) - 1) / int64(SitemapMaxURLs))
+	if pages == 0 {
+		pages = 1
+	}
+
+	origin := catu.GetConfiguration().Get("APP_ORIGIN")
+
+	index := sitemapIndex{XMLNS: sitemapXMLNS}
+	for i := 1; i <= pages; i++ {
+		index.Sitemaps = append(index.Sitemaps, SitemapURL{
+			Loc: origin + "/sitemap-taxonomy/" + strconv.Itoa(i) + ".xml",
+		})
+	}
+
+	return sitemapRender(c, index)
+}
+
+// SitemapHandler - One taxonomy sitemap, the :page param is like 1.xml
+func (r *Plugin) SitemapHandler(c echo.Context) error {
+	page, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".xml"))
+	if err != nil || page < 1 {
+		return echo.NotFoundHandler(c)
+	}
+
+	urls := []SitemapURL{}
+	err = SitemapFindURLsContext(c.Request().Context(), page, &urls)
+	if err != nil {
+		return errors.Wrap(err, "Plugin.SitemapHandler error on find urls")
+	}
+
+	if len(urls) == 0 && page > 1 {
+		return echo.NotFoundHandler(c)
+	}
+
+	return sitemapRender(c, sitemapURLSet{XMLNS: sitemapXMLNS, URLs: urls})
+}
+
+func sitemapRender(c echo.Context, data interface{}) error {
+	body, err := xml.Marshal(data)
+	if err != nil {
+		return errors.Wrap(err, "sitemapRender error on marshal xml")
+	}
+
+	return c.Blob(http.StatusOK, echo.MIMEApplicationXMLCharsetUTF8, append([]byte(xml.Header), body...))
+}
@@ -0,0 +1,53 @@
+package tags
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSitemap(t *testing.T) {
+	assert := assert.New(t)
+	app := GetAppInstance()
+	db := app.GetDB()
+
+	listed := TermModel{Text: "Listed", VocabularyName: "sitemapcategory"}
+	assert.Nil(listed.Save())
+	hidden := TermModel{Text: "Hidden", VocabularyName: "sitemapcategory", NoIndex: true}
+	assert.Nil(hidden.Save())
+	hiddenVocabularyTerm := TermModel{Text: "Other", VocabularyName: "sitemaphidden"}
+	assert.Nil(hiddenVocabularyTerm.Save())
+	assert.Nil(db.Create(&VocabularyModel{ID: 9002, Name: "sitemaphidden", NoIndex: true}).Error)
+
+	// one association updated after the term
+	assocUpdatedAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	assoc, _ := NewModelsterms("sitemapcategory", "sitemappost", "category", 1, listed.ID)
+	assoc.UpdatedAt = assocUpdatedAt
+	assert.Nil(db.Create(&assoc).Error)
+
+	maxURLs := SitemapMaxURLs
+	SitemapMaxURLs = 3
+	defer func() { SitemapMaxURLs = maxURLs }()
+
+	count, err := SitemapCount()
+	assert.Nil(err)
+
+	urls := map[string]string{}
+	pages := int((count + 2) / 3)
+	for page := 1; page <= pages; page++ {
+		pageURLs := []SitemapURL{}
+		assert.Nil(SitemapFindURLs(page, &pageURLs))
+		assert.LessOrEqual(len(pageURLs), 3)
+		for _, u := range pageURLs {
+			urls[u.Loc] = u.LastMod
+		}
+	}
+	assert.Equal(int(count), len(urls))
+
+	assert.Contains(urls, "/vocabulary/sitemapcategory")
+	assert.Equal(assocUpdatedAt.Format(time.RFC3339), urls[listed.GetPath()])
+	assert.NotContains(urls, hidden.GetPath())
+	assert.NotContains(urls, hiddenVocabularyTerm.GetPath())
+	assert.NotContains(urls, "/vocabulary/sitemaphidden")
+}
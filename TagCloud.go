@@ -0,0 +1,142 @@
+package tags
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-catupiry/catu"
+	"github.com/pkg/errors"
+)
+
+// Default number of weight classes used to render one tag cloud
+const TagCloudDefaultWeights = 10
+
+// TagCloudItem - One term in a tag cloud with its usage count and weight class
+type TagCloudItem struct {
+	ID             uint64 `gorm:"column:id" json:"id"`
+	Text           string `gorm:"column:text" json:"text"`
+	VocabularyName string `gorm:"column:vocabularyName" json:"vocabularyName"`
+	Count          int64  `gorm:"column:count" json:"count"`
+	Weight         int    `gorm:"-" json:"weight"`
+	LinkPermanent  string `gorm:"-" json:"linkPermanent"`
+}
+
+func (r *TagCloudItem) LoadPath() error {
+	t := TermModel{ID: r.ID, VocabularyName: r.VocabularyName}
+	t.LoadPath()
+	r.LinkPermanent = t.LinkPermanent
+	return nil
+}
+
+type TagCloudOpts struct {
+	VocabularyName string
+	// Optional filters applied in the modelsterms table
+	ModelName string
+	Field     string
+	Since     *time.Time
+	Until     *time.Time
+	// Max number of terms, the most used terms are selected
+	Limit int
+	// Number of weight classes, defaults to TagCloudDefaultWeights
+	Weights int
+}
+
+// Find the most used vocabulary terms and set their weight class, the result is sorted by text
+func TagCloudFind(opts *TagCloudOpts, records *[]TagCloudItem) error {
+	return TagCloudFindContext(context.Background(), opts, records)
+}
+
+// TagCloudFindContext - TagCloudFind using ctx in the database queries
+func TagCloudFindContext(ctx context.Context, opts *TagCloudOpts, records *[]TagCloudItem) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	query := db.Table("terms").
+		Select("terms.id AS id, terms.text AS text, terms.vocabularyName AS vocabularyName, COUNT(A.id) AS count").
+		Joins("INNER JOIN modelsterms AS A ON A.termId = terms.id").
+		Where("terms.vocabularyName = ?", opts.VocabularyName)
+
+	if opts.ModelName != "" {
+		query = query.Where("A.modelName = ?", opts.ModelName)
+	}
+
+	if opts.Field != "" {
+		query = query.Where("A.field = ?", opts.Field)
+	}
+
+	if opts.Since != nil {
+		query = query.Where("A.createdAt >= ?", opts.Since)
+	}
+
+	if opts.Until != nil {
+		query = query.Where("A.createdAt <= ?", opts.Until)
+	}
+
+	query = query.
+		Group("terms.id").
+		Group("terms.text").
+		Group("terms.vocabularyName").
+		Order("count DESC").
+		Order("terms.text ASC")
+
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+
+	err := query.Scan(records).Error
+	if err != nil {
+		return errors.Wrap(err, "TagCloudFind error on count terms")
+	}
+
+	weights := opts.Weights
+	if weights < 1 {
+		weights = TagCloudDefaultWeights
+	}
+
+	SetTagCloudWeights(*records, weights)
+
+	for i := range *records {
+		(*records)[i].LoadPath()
+	}
+
+	sort.SliceStable(*records, func(i, j int) bool {
+		return strings.ToLower((*records)[i].Text) < strings.ToLower((*records)[j].Text)
+	})
+
+	return nil
+}
+
+// Split the items in weight classes from 1 to weights with a logarithmic scale
+func SetTagCloudWeights(items []TagCloudItem, weights int) {
+	if len(items) == 0 {
+		return
+	}
+
+	min, max := items[0].Count, items[0].Count
+	for i := range items {
+		if items[i].Count < min {
+			min = items[i].Count
+		}
+		if items[i].Count > max {
+			max = items[i].Count
+		}
+	}
+
+	if min < 1 {
+		min = 1
+	}
+
+	for i := range items {
+		if max <= min || items[i].Count <= min {
+			items[i].Weight = 1
+			continue
+		}
+
+		scale := (math.Log(float64(items[i].Count)) - math.Log(float64(min))) /
+			(math.Log(float64(max)) - math.Log(float64(min)))
+
+		items[i].Weight = 1 + int(math.Round(scale*float64(weights-1)))
+	}
+}
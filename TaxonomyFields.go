@@ -0,0 +1,472 @@
+package tags
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-catupiry/catu"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Returned when one taxonomy struct tag is invalid
+var ErrTaxonomyFieldInvalid = errors.New("invalid taxonomy field")
+
+// TaxonomyStructField - One model struct field declared with the taxonomy tag, ex:
+//
+//	Tags []string `gorm:"-" taxonomy:"vocabulary=Tags;field=tags;multiple;create;lowercase"`
+//	Category string `gorm:"-" taxonomy:"vocabulary=Category;field=category"`
+//
+// Available settings: vocabulary (required), field (defaults to the struct field name with lowercase first letter),
+// model (defaults to the gorm table name), multiple, create and lowercase.
+// The struct field should be a []string or a string and ignored by gorm with gorm:"-"
+type TaxonomyStructField struct {
+	// Struct field name, ex: Tags
+	Name    string
+	IsSlice bool
+	Config  FieldConfigurationInterface
+}
+
+var taxonomyStructFieldsCache sync.Map
+
+// Parse the taxonomy struct tags of one model, the result is cached by model type
+func ParseTaxonomyFields(model interface{}) ([]TaxonomyStructField, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return []TaxonomyStructField{}, nil
+	}
+
+	if cached, ok := taxonomyStructFieldsCache.Load(t); ok {
+		return cached.([]TaxonomyStructField), nil
+	}
+
+	fields := []TaxonomyStructField{}
+	modelName := taxonomyModelName(t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("taxonomy")
+		if !ok || tag == "-" {
+			continue
+		}
+
+		f, err := parseTaxonomyStructField(sf, tag, modelName)
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, f)
+	}
+
+	taxonomyStructFieldsCache.Store(t, fields)
+
+	return fields, nil
+}
+
+func parseTaxonomyStructField(sf reflect.StructField, tag, modelName string) (TaxonomyStructField, error) {
+	f := TaxonomyStructField{Name: sf.Name}
+
+	switch {
+	case sf.Type.Kind() == reflect.String:
+	case sf.Type.Kind() == reflect.Slice && sf.Type.Elem().Kind() == reflect.String:
+		f.IsSlice = true
+	default:
+		return f, errors.Wrap(ErrTaxonomyFieldInvalid, sf.Name+" should be a string or []string")
+	}
+
+	cfg := &FieldConfiguration{
+		DB:               catu.GetDefaultDatabaseConnection(),
+		ModelName:        modelName,
+		FieldName:        strings.ToLower(sf.Name[:1]) + sf.Name[1:],
+		AssociationModel: ModelstermsModel{},
+		ModelToAssociate: TermModel{},
+	}
+
+	for _, setting := range strings.Split(tag, ";") {
+		kv := strings.SplitN(strings.TrimSpace(setting), "=", 2)
+		value := ""
+		if len(kv) == 2 {
+			value = strings.TrimSpace(kv[1])
+		}
+
+		switch kv[0] {
+		case "":
+		case "vocabulary":
+			cfg.VocabularyName = value
+		case "field":
+			cfg.FieldName = value
+		case "model":
+			cfg.ModelName = value
+		case "multiple":
+			cfg.FormFieldMultiple = true
+		case "create":
+			cfg.CanCreate = true
+		case "lowercase":
+			cfg.OnlyLowercase = true
+		default:
+			return f, errors.Wrap(ErrTaxonomyFieldInvalid, sf.Name+" unknown setting "+kv[0])
+		}
+	}
+
+	if cfg.VocabularyName == "" || cfg.FieldName == "" || cfg.ModelName == "" {
+		return f, errors.Wrap(ErrTaxonomyFieldInvalid, sf.Name+" vocabulary, field and model are required")
+	}
+
+	if cfg.FormFieldMultiple && !f.IsSlice {
+		return f, errors.Wrap(ErrTaxonomyFieldInvalid, sf.Name+" multiple fields should be a []string")
+	}
+
+	f.Config = cfg
+
+	return f, nil
+}
+
+// Get the model name used in the terms associations, the same as the gorm table name
+func taxonomyModelName(t reflect.Type) string {
+	if tabler, ok := reflect.New(t).Interface().(schema.Tabler); ok {
+		return tabler.TableName()
+	}
+
+	return schema.NamingStrategy{}.TableName(t.Name())
+}
+
+// Register gorm callbacks to load the taxonomy struct fields after query, save them after create and update
+// and clear the model terms after delete
+func RegisterTaxonomyCallbacks(db *gorm.DB) error {
+	if db.Callback().Query().Get("taxonomy:load_terms") != nil {
+		return nil
+	}
+
+	err := db.Callback().Query().After("gorm:query").Register("taxonomy:load_terms", taxonomyLoadTermsCallback)
+	if err != nil {
+		return errors.Wrap(err, "RegisterTaxonomyCallbacks error on register query callback")
+	}
+
+	// run before commit so the record and its terms are saved or rolled back together
+	err = db.Callback().Create().Before("gorm:commit_or_rollback_transaction").Register("taxonomy:save_terms", taxonomySaveTermsCallback)
+	if err != nil {
+		return errors.Wrap(err, "RegisterTaxonomyCallbacks error on register create callback")
+	}
+
+	err = db.Callback().Update().Before("gorm:commit_or_rollback_transaction").Register("taxonomy:save_terms", taxonomySaveTermsCallback)
+	if err != nil {
+		return errors.Wrap(err, "RegisterTaxonomyCallbacks error on register update callback")
+	}
+
+	// find the deleted ids before the delete, the records can be deleted with conditions, ex: db.Where(...).Delete(&Model{})
+	err = db.Callback().Delete().Before("gorm:delete").Register("taxonomy:find_deleted", taxonomyFindDeletedCallback)
+	if err != nil {
+		return errors.Wrap(err, "RegisterTaxonomyCallbacks error on register before delete callback")
+	}
+
+	err = db.Callback().Delete().Before("gorm:commit_or_rollback_transaction").Register("taxonomy:clear_terms", taxonomyClearTermsCallback)
+	if err != nil {
+		return errors.Wrap(err, "RegisterTaxonomyCallbacks error on register delete callback")
+	}
+
+	return nil
+}
+
+// Get the statement taxonomy fields, returns an empty list if the model has no taxonomy fields
+func taxonomyStatementFields(db *gorm.DB) []TaxonomyStructField {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return []TaxonomyStructField{}
+	}
+
+	fields, err := ParseTaxonomyFields(reflect.New(db.Statement.Schema.ModelType).Interface())
+	if err != nil {
+		db.AddError(err)
+		return []TaxonomyStructField{}
+	}
+
+	return fields
+}
+
+// Get the statement records with the model id, records with empty ids are skipped
+func taxonomyStatementRecords(db *gorm.DB) (records []reflect.Value, ids []string) {
+	rv := db.Statement.ReflectValue
+	values := []reflect.Value{}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		values = append(values, rv)
+	}
+
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	for _, v := range values {
+		if v.Kind() != reflect.Struct || v.Type() != db.Statement.Schema.ModelType {
+			continue
+		}
+
+		id, zero := pk.ValueOf(db.Statement.Context, v)
+		if zero {
+			continue
+		}
+
+		records = append(records, v)
+		ids = append(ids, fmt.Sprint(id))
+	}
+
+	return records, ids
+}
+
+// Check if the statement dest is the model, skipping updates with maps or other structs
+func taxonomyDestIsModel(db *gorm.DB) bool {
+	if db.Statement.Dest == nil {
+		return false
+	}
+
+	t := reflect.TypeOf(db.Statement.Dest)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+
+	return t == db.Statement.Schema.ModelType
+}
+
+// Check if the field is selected and not omitted in the statement
+func taxonomyFieldIsSelected(db *gorm.DB, f *TaxonomyStructField) bool {
+	for _, name := range db.Statement.Omits {
+		if name == f.Name || name == f.Config.GetFieldName() {
+			return false
+		}
+	}
+
+	if len(db.Statement.Selects) == 0 {
+		return true
+	}
+
+	for _, name := range db.Statement.Selects {
+		if name == "*" || name == f.Name || name == f.Config.GetFieldName() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func taxonomyLoadTermsCallback(db *gorm.DB) {
+	fields := taxonomyStatementFields(db)
+	if len(fields) == 0 {
+		return
+	}
+
+	records, ids := taxonomyStatementRecords(db)
+	if len(records) == 0 {
+		return
+	}
+
+	type termRow struct {
+		ModelID string `gorm:"column:modelId"`
+		Text    string `gorm:"column:text"`
+	}
+
+	for i := range fields {
+		f := fields[i]
+
+		rows := []termRow{}
+		err := db.Session(&gorm.Session{NewDB: true}).
+			Table("modelsterms AS A").
+			Select("A.modelId, T.text").
+			Joins("INNER JOIN terms AS T ON T.id = A.termId").
+			Where("A.modelName = ? AND A.field = ? AND A.modelId IN ?", f.Config.GetModelName(), f.Config.GetFieldName(), ids).
+			Order(clause.OrderByColumn{Column: clause.Column{Table: "A", Name: "order"}}).
+			Order("A.id ASC").
+			Scan(&rows).Error
+		if err != nil {
+			db.AddError(errors.Wrap(err, "taxonomyLoadTermsCallback error on find terms"))
+			return
+		}
+
+		for j := range records {
+			texts := []string{}
+			for _, row := range rows {
+				if row.ModelID == ids[j] {
+					texts = append(texts, row.Text)
+				}
+			}
+
+			v := records[j].FieldByName(f.Name)
+			if !v.CanSet() {
+				continue
+			}
+
+			if f.IsSlice {
+				v.Set(reflect.ValueOf(texts).Convert(v.Type()))
+			} else if len(texts) > 0 {
+				v.SetString(texts[0])
+			} else {
+				v.SetString("")
+			}
+		}
+	}
+}
+
+// Save the taxonomy fields, nil slices and empty strings are skipped, use an empty slice to remove all terms
+func taxonomySaveTermsCallback(db *gorm.DB) {
+	fields := taxonomyStatementFields(db)
+	if len(fields) == 0 || !taxonomyDestIsModel(db) {
+		return
+	}
+
+	records, ids := taxonomyStatementRecords(db)
+
+	for i := range fields {
+		f := fields[i]
+		if !taxonomyFieldIsSelected(db, &f) {
+			continue
+		}
+
+		for j := range records {
+			v := records[j].FieldByName(f.Name)
+
+			var texts []string
+			if f.IsSlice {
+				if v.IsNil() {
+					continue
+				}
+
+				texts = v.Convert(reflect.TypeOf(texts)).Interface().([]string)
+			} else {
+				if v.String() == "" {
+					continue
+				}
+
+				texts = []string{v.String()}
+			}
+
+			err := FieldWithDB(f.Config, db.Session(&gorm.Session{NewDB: true})).Update(ids[j], texts)
+			if err != nil {
+				db.AddError(errors.Wrap(err, "taxonomySaveTermsCallback error on update field "+f.Config.GetFieldName()))
+				return
+			}
+		}
+	}
+}
+
+const taxonomyDeletedIDsKey = "taxonomy:deleted_ids"
+
+// Get the plugin used to clear the registered fields, returns nil if the app or plugin is not set
+func taxonomyGetPlugin() *Plugin {
+	if app := catu.GetApp(); app != nil {
+		return GetPluginFromApp(app)
+	}
+
+	return nil
+}
+
+// Find the ids of the records to delete with the statement conditions and the records primary keys
+func taxonomyFindDeletedCallback(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return
+	}
+
+	p := taxonomyGetPlugin()
+	if len(taxonomyStatementFields(db)) == 0 && (p == nil || len(p.GetModelFields(db.Statement.Table)) == 0) {
+		return
+	}
+
+	pk := db.Statement.Schema.PrioritizedPrimaryField
+	_, recordIds := taxonomyStatementRecords(db)
+	where, hasWhere := db.Statement.Clauses["WHERE"].Expression.(clause.Where)
+
+	// without conditions gorm returns ErrMissingWhereClause
+	if len(recordIds) == 0 && !hasWhere && !db.AllowGlobalUpdate {
+		return
+	}
+
+	query := db.Session(&gorm.Session{NewDB: true}).
+		Model(reflect.New(db.Statement.Schema.ModelType).Interface()).
+		Table(db.Statement.Table)
+
+	if db.Statement.Unscoped {
+		query = query.Unscoped()
+	}
+
+	if hasWhere {
+		query = query.Clauses(where)
+	}
+
+	if len(recordIds) > 0 {
+		query = query.Where(clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Values: taxonomyValues(recordIds)})
+	}
+
+	values := reflect.New(reflect.SliceOf(pk.FieldType))
+	err := query.Pluck(pk.DBName, values.Interface()).Error
+	if err != nil {
+		db.AddError(errors.Wrap(err, "taxonomyFindDeletedCallback error on find records"))
+		return
+	}
+
+	ids := []string{}
+	for i := 0; i < values.Elem().Len(); i++ {
+		ids = append(ids, fmt.Sprint(values.Elem().Index(i).Interface()))
+	}
+
+	db.InstanceSet(taxonomyDeletedIDsKey, ids)
+}
+
+func taxonomyValues(ids []string) []interface{} {
+	values := make([]interface{}, len(ids))
+	for i := range ids {
+		values[i] = ids[i]
+	}
+
+	return values
+}
+
+// Clear the terms of the deleted records, from the taxonomy struct fields and the fields registered in the
+// plugin for the table
+func taxonomyClearTermsCallback(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.Schema.PrioritizedPrimaryField == nil {
+		return
+	}
+
+	v, ok := db.InstanceGet(taxonomyDeletedIDsKey)
+	if !ok {
+		return
+	}
+	ids := v.([]string)
+
+	fields := taxonomyStatementFields(db)
+	p := taxonomyGetPlugin()
+	tx := db.Session(&gorm.Session{NewDB: true})
+
+	for _, id := range ids {
+		// clear all terms of each model name, fields with the model option can use other model names
+		cleared := map[string]bool{}
+		for i := range fields {
+			modelName := fields[i].Config.GetModelName()
+			if cleared[modelName] {
+				continue
+			}
+			cleared[modelName] = true
+
+			err := FieldWithDB(fields[i].Config, tx).Clear(id)
+			if err != nil {
+				db.AddError(errors.Wrap(err, "taxonomyClearTermsCallback error on clear terms"))
+				return
+			}
+		}
+
+		if p != nil {
+			err := p.ClearRecordWithDB(tx, db.Statement.Table, id)
+			if err != nil {
+				db.AddError(errors.Wrap(err, "taxonomyClearTermsCallback error on clear registered fields"))
+				return
+			}
+		}
+	}
+}
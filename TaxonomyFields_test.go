@@ -0,0 +1,127 @@
+package tags
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type TaxonomyOverrideStub struct {
+	ID    uint64   `json:"id"`
+	Title string   `json:"title"`
+	Tags  []string `gorm:"-" json:"tags" taxonomy:"vocabulary=Tags;field=tags;multiple;create"`
+	Topic string   `gorm:"-" json:"topic" taxonomy:"vocabulary=Topic;field=topic;model=override_topics;create"`
+}
+
+type TaxonomyContentStub struct {
+	ID       uint64   `json:"id"`
+	Title    string   `json:"title"`
+	Tags     []string `gorm:"-" json:"tags" taxonomy:"vocabulary=Tags;field=tags;multiple;create;lowercase"`
+	Category string   `gorm:"-" json:"category" taxonomy:"vocabulary=Category;field=category;create"`
+}
+
+func (r *TaxonomyContentStub) GetIDString() string {
+	return strconv.FormatUint(r.ID, 10)
+}
+
+func TestTaxonomyFieldsCallbacks(t *testing.T) {
+	assert := assert.New(t)
+
+	app := GetAppInstance()
+	db := app.GetDB()
+
+	err := RegisterTaxonomyCallbacks(db)
+	assert.Nil(err)
+
+	t.Run("Should parse the taxonomy struct tags", func(t *testing.T) {
+		fields, err := ParseTaxonomyFields(&TaxonomyContentStub{})
+		assert.Nil(err)
+		assert.Equal(2, len(fields))
+
+		assert.Equal("taxonomy_content_stubs", fields[0].Config.GetModelName())
+		assert.Equal("tags", fields[0].Config.GetFieldName())
+		assert.True(fields[0].Config.IsFormFieldMultiple())
+		assert.True(FieldIsOnlyLowercase(fields[0].Config))
+		assert.False(fields[1].Config.IsFormFieldMultiple())
+	})
+
+	t.Run("Should save, load and clear terms with the model", func(t *testing.T) {
+		record := TaxonomyContentStub{
+			Title:    "Hello",
+			Tags:     []string{"Go", "gorm"},
+			Category: "Programming",
+		}
+
+		err := db.Create(&record).Error
+		assert.Nil(err)
+
+		var saved TaxonomyContentStub
+		err = db.First(&saved, record.ID).Error
+		assert.Nil(err)
+		assert.Equal([]string{"go", "gorm"}, saved.Tags)
+		assert.Equal("Programming", saved.Category)
+
+		saved.Tags = []string{"gorm"}
+		err = db.Save(&saved).Error
+		assert.Nil(err)
+
+		// updates without the taxonomy fields keep the terms
+		err = db.Model(&saved).Update("title", "Hello 2").Error
+		assert.Nil(err)
+
+		list := []TaxonomyContentStub{}
+		err = db.Find(&list, record.ID).Error
+		assert.Nil(err)
+		assert.Equal(1, len(list))
+		assert.Equal([]string{"gorm"}, list[0].Tags)
+		assert.Equal("Programming", list[0].Category)
+
+		err = db.Delete(&saved).Error
+		assert.Nil(err)
+
+		var count int64
+		err = db.Model(&ModelstermsModel{}).
+			Where("modelName = ? AND modelId = ?", "taxonomy_content_stubs", record.ID).
+			Count(&count).Error
+		assert.Nil(err)
+		assert.Equal(int64(0), count)
+	})
+
+	countAssocs := func(modelName string, ids ...uint64) int64 {
+		var count int64
+		err := db.Model(&ModelstermsModel{}).Where("modelName = ? AND modelId IN ?", modelName, ids).Count(&count).Error
+		assert.Nil(err)
+		return count
+	}
+
+	t.Run("Should clear terms of records deleted by id or conditions", func(t *testing.T) {
+		records := []TaxonomyContentStub{
+			{Title: "By id", Tags: []string{"go"}},
+			{Title: "By conditions", Tags: []string{"go"}, Category: "Programming"},
+			{Title: "Kept", Tags: []string{"go"}},
+		}
+		assert.Nil(db.Create(&records).Error)
+		assert.Equal(int64(4), countAssocs("taxonomy_content_stubs", records[0].ID, records[1].ID, records[2].ID))
+
+		assert.Nil(db.Delete(&TaxonomyContentStub{}, records[0].ID).Error)
+		assert.Equal(int64(0), countAssocs("taxonomy_content_stubs", records[0].ID))
+
+		assert.Nil(db.Where("title = ?", "By conditions").Delete(&TaxonomyContentStub{}).Error)
+		assert.Equal(int64(0), countAssocs("taxonomy_content_stubs", records[1].ID))
+
+		assert.Equal(int64(1), countAssocs("taxonomy_content_stubs", records[2].ID))
+	})
+
+	t.Run("Should clear the terms of fields with the model option", func(t *testing.T) {
+		assert.Nil(db.AutoMigrate(&TaxonomyOverrideStub{}))
+
+		record := TaxonomyOverrideStub{Title: "Hello", Tags: []string{"go"}, Topic: "news"}
+		assert.Nil(db.Create(&record).Error)
+		assert.Equal(int64(1), countAssocs("override_topics", record.ID))
+
+		assert.Nil(db.Delete(&TaxonomyOverrideStub{}, record.ID).Error)
+		assert.Equal(int64(0), countAssocs("taxonomy_override_stubs", record.ID))
+		assert.Equal(int64(0), countAssocs("override_topics", record.ID))
+	})
+}
@@ -0,0 +1,258 @@
+package tags
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-catupiry/catu"
+	"github.com/go-catupiry/catu/helpers"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Returned when one alias text is already used by other term or alias in the same vocabulary
+var ErrTermAliasConflict = errors.New("term alias is already used in this vocabulary")
+
+// TermAliasModel - Alternative spelling for one term, resolved to the term on text lookups
+type TermAliasModel struct {
+	ID             uint64    `gorm:"primaryKey;column:id" json:"id"`
+	TermID         uint64    `gorm:"index:term_aliases_termId_IDX;column:termId;type:int(11);not null" json:"termId"`
+	Text           string    `gorm:"index:term_aliases_text_IDX;column:text;type:varchar(255);not null" json:"text"`
+	VocabularyName string    `gorm:"index:term_aliases_text_IDX;column:vocabularyName;type:varchar(255);not null;default:Tags" json:"vocabularyName"`
+	CreatedAt      time.Time `gorm:"column:createdAt;type:datetime;not null" json:"createdAt"`
+	UpdatedAt      time.Time `gorm:"column:updatedAt;type:datetime;not null" json:"updatedAt"`
+}
+
+// TableName - Set db table name for term alias model
+func (r *TermAliasModel) TableName() string {
+	return "term_aliases"
+}
+
+// Load term aliases texts in r.Aliases
+func (r *TermModel) LoadAliases() error {
+	return r.LoadAliasesContext(context.Background())
+}
+
+// LoadAliasesContext - LoadAliases using ctx in the database queries
+func (r *TermModel) LoadAliasesContext(ctx context.Context) error {
+	records := []TermModel{*r}
+	err := TermLoadManyAliasesContext(ctx, records)
+	if err != nil {
+		return err
+	}
+
+	r.Aliases = records[0].Aliases
+	return nil
+}
+
+// Load aliases for a term list with only one query
+func TermLoadManyAliases(records []TermModel) error {
+	return TermLoadManyAliasesContext(context.Background(), records)
+}
+
+// TermLoadManyAliasesContext - TermLoadManyAliases using ctx in the database queries
+func TermLoadManyAliasesContext(ctx context.Context, records []TermModel) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	ids := []uint64{}
+	for i := range records {
+		ids = append(ids, records[i].ID)
+	}
+
+	aliases := []TermAliasModel{}
+	err := db.Where("termId IN ?", ids).
+		Order("text ASC").
+		Find(&aliases).Error
+	if err != nil {
+		return errors.Wrap(err, "TermLoadManyAliases error on find aliases")
+	}
+
+	for i := range records {
+		records[i].Aliases = []string{}
+		for j := range aliases {
+			if aliases[j].TermID == records[i].ID {
+				records[i].Aliases = append(records[i].Aliases, aliases[j].Text)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Replace the term aliases with the r.Aliases list
+func (r *TermModel) SaveAliases() error {
+	return r.SaveAliasesContext(context.Background())
+}
+
+// SaveAliasesContext - SaveAliases using ctx in the database queries
+func (r *TermModel) SaveAliasesContext(ctx context.Context) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return r.saveAliases(tx)
+	})
+}
+
+// Replace the term aliases in the tx transaction, used by SaveAliases and Save
+func (r *TermModel) saveAliases(tx *gorm.DB) error {
+	aliases := []string{}
+	for i := range r.Aliases {
+		text := strings.TrimSpace(r.Aliases[i])
+		if text == "" || text == r.Text || helpers.SliceContains(aliases, text) {
+			continue
+		}
+		aliases = append(aliases, text)
+	}
+
+	if len(aliases) > 0 {
+		var count int64
+		err := tx.Model(&TermModel{}).
+			Where("vocabularyName = ? AND text IN ? AND id != ?", r.VocabularyName, aliases, r.ID).
+			Count(&count).Error
+		if err != nil {
+			return errors.Wrap(err, "TermModel.SaveAliases error on check terms")
+		}
+
+		if count == 0 {
+			err = tx.Model(&TermAliasModel{}).
+				Where("vocabularyName = ? AND text IN ? AND termId != ?", r.VocabularyName, aliases, r.ID).
+				Count(&count).Error
+			if err != nil {
+				return errors.Wrap(err, "TermModel.SaveAliases error on check aliases")
+			}
+		}
+
+		if count > 0 {
+			return ErrTermAliasConflict
+		}
+	}
+
+	saved := []TermAliasModel{}
+	err := tx.Where("termId = ?", r.ID).Find(&saved).Error
+	if err != nil {
+		return errors.Wrap(err, "TermModel.SaveAliases error on find saved aliases")
+	}
+
+	savedTexts := []string{}
+	idsToDelete := []uint64{}
+	for i := range saved {
+		if helpers.SliceContains(aliases, saved[i].Text) && saved[i].VocabularyName == r.VocabularyName {
+			savedTexts = append(savedTexts, saved[i].Text)
+		} else {
+			idsToDelete = append(idsToDelete, saved[i].ID)
+		}
+	}
+
+	if len(idsToDelete) > 0 {
+		err = tx.Where("id IN ?", idsToDelete).Delete(&TermAliasModel{}).Error
+		if err != nil {
+			return errors.Wrap(err, "TermModel.SaveAliases error on delete aliases")
+		}
+	}
+
+	toCreate := []TermAliasModel{}
+	for i := range aliases {
+		if !helpers.SliceContains(savedTexts, aliases[i]) {
+			toCreate = append(toCreate, TermAliasModel{
+				TermID:         r.ID,
+				Text:           aliases[i],
+				VocabularyName: r.VocabularyName,
+			})
+		}
+	}
+
+	if len(toCreate) > 0 {
+		err = tx.Create(&toCreate).Error
+		if err != nil {
+			return errors.Wrap(err, "TermModel.SaveAliases error on create aliases")
+		}
+	}
+
+	r.Aliases = aliases
+
+	return nil
+}
+
+// Find one alias by text in the vocabulary, record.ID will be 0 if not found
+func TermAliasFindOneByText(text, vocabularyName string, record *TermAliasModel) error {
+	return TermAliasFindOneByTextContext(context.Background(), text, vocabularyName, record)
+}
+
+// TermAliasFindOneByTextContext - TermAliasFindOneByText using ctx in the database queries
+func TermAliasFindOneByTextContext(ctx context.Context, text, vocabularyName string, record *TermAliasModel) error {
+	return termAliasFindOneByText(catu.GetDefaultDatabaseConnection().WithContext(ctx), text, vocabularyName, record)
+}
+
+func termAliasFindOneByText(db *gorm.DB, text, vocabularyName string, record *TermAliasModel) error {
+	return db.Where("text = ? AND vocabularyName = ?", text, vocabularyName).
+		Limit(1).
+		Find(record).Error
+}
+
+// Replace alias texts with its term texts, keeping the order and removing duplicates.
+// Texts without term or alias are returned as is
+func TermResolveAliasTexts(texts []string, vocabularyName string) ([]string, error) {
+	return TermResolveAliasTextsContext(context.Background(), texts, vocabularyName)
+}
+
+// TermResolveAliasTextsContext - TermResolveAliasTexts using ctx in the database queries
+func TermResolveAliasTextsContext(ctx context.Context, texts []string, vocabularyName string) ([]string, error) {
+	return termResolveAliasTexts(catu.GetDefaultDatabaseConnection().WithContext(ctx), texts, vocabularyName)
+}
+
+func termResolveAliasTexts(db *gorm.DB, texts []string, vocabularyName string) ([]string, error) {
+	if len(texts) == 0 {
+		return texts, nil
+	}
+
+	aliases := []TermAliasModel{}
+	err := db.Where("text IN ? AND vocabularyName = ?", texts, vocabularyName).
+		Find(&aliases).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "TermResolveAliasTexts error on find aliases")
+	}
+
+	if len(aliases) == 0 {
+		return texts, nil
+	}
+
+	termIds := []uint64{}
+	for i := range aliases {
+		termIds = append(termIds, aliases[i].TermID)
+	}
+
+	terms := []TermModel{}
+	err = db.Where("id IN ?", termIds).Find(&terms).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "TermResolveAliasTexts error on find alias terms")
+	}
+
+	canonical := map[string]string{}
+	for i := range aliases {
+		for j := range terms {
+			if terms[j].ID == aliases[i].TermID {
+				canonical[aliases[i].Text] = terms[j].Text
+				break
+			}
+		}
+	}
+
+	resolved := []string{}
+	for i := range texts {
+		text := texts[i]
+		if c, ok := canonical[text]; ok {
+			text = c
+		}
+
+		if !helpers.SliceContains(resolved, text) {
+			resolved = append(resolved, text)
+		}
+	}
+
+	return resolved, nil
+}
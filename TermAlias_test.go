@@ -0,0 +1,76 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTermAliases(t *testing.T) {
+	assert := assert.New(t)
+	db := GetAppInstance().GetDB()
+
+	golang := TermModel{Text: "go", VocabularyName: "aliastags", Aliases: []string{"golang", " golang ", "go", ""}}
+	assert.Nil(golang.Save())
+	assert.Equal([]string{"golang"}, golang.Aliases)
+
+	loaded := TermModel{}
+	assert.Nil(TermFindOne(golang.GetIDString(), &loaded))
+	assert.Nil(loaded.LoadAliases())
+	assert.Equal([]string{"golang"}, loaded.Aliases)
+
+	resolved, err := TermResolveAliasTexts([]string{"golang", "rust", "go"}, "aliastags")
+	assert.Nil(err)
+	assert.Equal([]string{"go", "rust"}, resolved)
+
+	// the same alias text is allowed in other vocabularies
+	other := TermModel{Text: "golang", VocabularyName: "aliasother"}
+	assert.Nil(other.Save())
+
+	t.Run("Should reject aliases used by other terms", func(t *testing.T) {
+		rust := TermModel{Text: "rust", VocabularyName: "aliastags"}
+		assert.Nil(rust.Save())
+
+		rust.Aliases = []string{"golang"}
+		assert.ErrorIs(rust.Save(), ErrTermAliasConflict)
+		rust.Aliases = []string{"go"}
+		assert.ErrorIs(rust.Save(), ErrTermAliasConflict)
+	})
+
+	t.Run("Should not create the term on alias conflict", func(t *testing.T) {
+		var before int64
+		assert.Nil(db.Model(&TermModel{}).Where("vocabularyName = ?", "aliastags").Count(&before).Error)
+
+		python := TermModel{Text: "python", VocabularyName: "aliastags", Aliases: []string{"golang"}}
+		assert.ErrorIs(python.Save(), ErrTermAliasConflict)
+		assert.Equal(uint64(0), python.ID)
+
+		var after int64
+		assert.Nil(db.Model(&TermModel{}).Where("vocabularyName = ?", "aliastags").Count(&after).Error)
+		assert.Equal(before, after)
+	})
+
+	t.Run("Should not save one renamed term on alias conflict", func(t *testing.T) {
+		java := TermModel{Text: "java", VocabularyName: "aliastags"}
+		assert.Nil(java.Save())
+
+		java.Text = "kotlin"
+		java.Aliases = []string{"golang"}
+		assert.ErrorIs(java.Save(), ErrTermAliasConflict)
+
+		saved := TermModel{}
+		assert.Nil(TermFindOne(java.GetIDString(), &saved))
+		assert.Equal("java", saved.Text)
+		assert.Equal("java", saved.Slug)
+
+		slugRedirect := TermModel{}
+		assert.Nil(TermFindOneByOldSlug("java", "aliastags", &slugRedirect))
+		assert.Equal(uint64(0), slugRedirect.ID)
+	})
+
+	t.Run("Should reject new terms with one alias text", func(t *testing.T) {
+		term := TermModel{Text: "golang", VocabularyName: "aliastags"}
+		assert.ErrorIs(term.Save(), ErrTermAliasConflict)
+		assert.Equal(uint64(0), term.ID)
+	})
+}
@@ -0,0 +1,108 @@
+package tags
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-catupiry/catu"
+	"github.com/gosimple/unidecode"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Max autocomplete items returned in one request
+const TermAutocompleteMaxLimit = 50
+
+// TermAutocompleteItem - One autocomplete suggestion
+type TermAutocompleteItem struct {
+	ID    uint64 `gorm:"column:id" json:"id"`
+	Text  string `gorm:"column:text" json:"text"`
+	Usage int64  `gorm:"column:usageCount" json:"usage"`
+}
+
+type TermAutocompleteOpts struct {
+	VocabularyName string
+	Q              string
+	// Defaults to 10, max TermAutocompleteMaxLimit
+	Limit int
+}
+
+// Get the text used in term searches, lowercase and without accents, ex: Ação -> acao
+func TermSearchText(text string) string {
+	return strings.ToLower(strings.TrimSpace(unidecode.Unidecode(text)))
+}
+
+// BeforeSave - Set the term search text
+func (r *TermModel) BeforeSave(tx *gorm.DB) error {
+	r.SearchText = TermSearchText(r.Text)
+	return nil
+}
+
+// Find vocabulary terms containing the q text. Exact matches come first, then prefix matches and then
+// other matches, sorted by usage in each group
+func TermAutocomplete(opts *TermAutocompleteOpts, records *[]TermAutocompleteItem) error {
+	return TermAutocompleteContext(context.Background(), opts, records)
+}
+
+// TermAutocompleteContext - TermAutocomplete using ctx in the database queries
+func TermAutocompleteContext(ctx context.Context, opts *TermAutocompleteOpts, records *[]TermAutocompleteItem) error {
+	q := TermSearchText(opts.Q)
+	if q == "" {
+		return nil
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > TermAutocompleteMaxLimit {
+		limit = TermAutocompleteMaxLimit
+	}
+
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	err := db.Model(&TermModel{}).
+		Select("id, text, usageCount").
+		Where("vocabularyName = ? AND INSTR(searchText, ?) > 0", opts.VocabularyName, q).
+		// one expression, gorm drops expressions merged with other order columns
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "CASE WHEN searchText = ? THEN 0 WHEN INSTR(searchText, ?) = 1 THEN 1 ELSE 2 END, usageCount DESC, text ASC",
+			Vars: []interface{}{q, q},
+		}}).
+		Limit(limit).
+		Find(records).Error
+	if err != nil {
+		return errors.Wrap(err, "TermAutocomplete error on find terms")
+	}
+
+	return nil
+}
+
+// Set the search text of terms saved before the autocomplete support
+func TermEnsureSearchTexts() error {
+	return TermEnsureSearchTextsContext(context.Background())
+}
+
+// TermEnsureSearchTextsContext - TermEnsureSearchTexts using ctx in the database queries
+func TermEnsureSearchTextsContext(ctx context.Context) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	records := []TermModel{}
+	err := db.Select("id, text").
+		Where("searchText IS NULL OR searchText = ?", "").
+		Find(&records).Error
+	if err != nil {
+		return errors.Wrap(err, "TermEnsureSearchTexts error on find terms")
+	}
+
+	for i := range records {
+		err = db.Model(&records[i]).
+			UpdateColumn("searchText", TermSearchText(records[i].Text)).Error
+		if err != nil {
+			return errors.Wrap(err, "TermEnsureSearchTexts error on update term")
+		}
+	}
+
+	return nil
+}
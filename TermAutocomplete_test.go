@@ -0,0 +1,48 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTermAutocomplete(t *testing.T) {
+	assert := assert.New(t)
+	GetAppInstance()
+
+	vocabularyName := "autocomplete"
+	for _, text := range []string{"Ação", "Reação", "Acaoria", "Outro"} {
+		term := TermModel{Text: text, VocabularyName: vocabularyName}
+		err := term.Save()
+		assert.Nil(err)
+	}
+
+	records := []TermAutocompleteItem{}
+	err := TermAutocomplete(&TermAutocompleteOpts{VocabularyName: vocabularyName, Q: "acao"}, &records)
+	assert.Nil(err)
+	assert.Equal(3, len(records))
+	assert.Equal("Ação", records[0].Text)
+	assert.Equal("Acaoria", records[1].Text)
+	assert.Equal("Reação", records[2].Text)
+}
+
+func TestTermAutocomplete_Migrate(t *testing.T) {
+	assert := assert.New(t)
+	app := GetAppInstance()
+	db := app.GetDB()
+
+	// term saved before the searchText column
+	term := TermModel{Text: "Canção", VocabularyName: "autocompletemigrate"}
+	assert.Nil(term.Save())
+	assert.Nil(db.Model(&term).UpdateColumn("searchText", "").Error)
+
+	records := []TermAutocompleteItem{}
+	assert.Nil(TermAutocomplete(&TermAutocompleteOpts{VocabularyName: "autocompletemigrate", Q: "cancao"}, &records))
+	assert.Equal(0, len(records))
+
+	assert.Nil(NewPlugin(&PluginCfgs{}).Migrate(app))
+
+	assert.Nil(TermAutocomplete(&TermAutocompleteOpts{VocabularyName: "autocompletemigrate", Q: "cancao"}, &records))
+	assert.Equal(1, len(records))
+	assert.Equal("Canção", records[0].Text)
+}
@@ -1,13 +1,17 @@
 package tags
 
 import (
+	"context"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-catupiry/catu"
 	"github.com/go-catupiry/metatags"
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 type TermTextsResponse struct {
@@ -24,6 +28,10 @@ type TermCountJSONResponse struct {
 	catu.BaseMetaResponse
 }
 
+type TermTreeJSONResponse struct {
+	Records []*TermTreeNode `json:"term"`
+}
+
 type TermFindOneJSONResponse struct {
 	Record *TermModel `json:"term"`
 }
@@ -32,6 +40,10 @@ type TermBodyRequest struct {
 	Record *TermModel `json:"term"`
 }
 
+type TermMergeBodyRequest struct {
+	TargetID uint64 `json:"targetId"`
+}
+
 type TermTeaserTPL struct {
 	Ctx    *catu.RequestContext
 	Record *TermModel
@@ -54,7 +66,7 @@ func (ctl *TermController) Query(c echo.Context) error {
 
 	var count int64
 	var records []TermModel
-	err = TermQueryAndCountReq(&TermQueryOpts{
+	err = TermQueryAndCountReqContext(c.Request().Context(), &TermQueryOpts{
 		Records: &records,
 		Count:   &count,
 		Limit:   RequestContext.GetLimit(),
@@ -75,7 +87,17 @@ func (ctl *TermController) Query(c echo.Context) error {
 	}).Debug("Query count result")
 
 	for i := range records {
-		records[i].LoadData()
+		records[i].LoadTeaserData()
+	}
+
+	err = TermLoadManyAliasesContext(c.Request().Context(), records)
+	if err != nil {
+		return errors.Wrap(err, "TermController.Query error on load aliases")
+	}
+
+	err = TermLoadManyTranslationsContext(c.Request().Context(), records)
+	if err != nil {
+		return errors.Wrap(err, "TermController.Query error on load translations")
 	}
 
 	resp := TermListJSONResponse{
@@ -121,12 +143,20 @@ func (ctl *TermController) Create(c echo.Context) error {
 		"body": body,
 	}).Info("TermController.Create params")
 
-	err = record.Save()
+	err = ctl.validateParent(c.Request().Context(), record)
 	if err != nil {
 		return err
 	}
 
-	err = record.LoadData()
+	err = record.SaveContext(c.Request().Context())
+	if err != nil {
+		if errors.Is(err, ErrTermAliasConflict) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+
+	err = record.LoadDataContext(c.Request().Context())
 	if err != nil {
 		return err
 	}
@@ -143,7 +173,7 @@ func (ctl *TermController) Count(c echo.Context) error {
 	RequestContext := c.(*catu.RequestContext)
 
 	var count int64
-	err = TermCountReq(&TermQueryOpts{
+	err = TermCountReqContext(c.Request().Context(), &TermQueryOpts{
 		Count:  &count,
 		Limit:  RequestContext.GetLimit(),
 		Offset: RequestContext.GetOffset(),
@@ -174,7 +204,7 @@ func (ctl *TermController) FindOne(c echo.Context) error {
 	}).Debug("TermController.FindOne id from params")
 
 	record := TermModel{}
-	err := TermFindOne(id, &record)
+	err := TermFindOneContext(c.Request().Context(), id, &record)
 	if err != nil {
 		return err
 	}
@@ -190,7 +220,7 @@ func (ctl *TermController) FindOne(c echo.Context) error {
 		}
 	}
 
-	record.LoadData()
+	record.LoadDataContext(c.Request().Context())
 
 	resp := TermFindOneJSONResponse{
 		Record: &record,
@@ -217,7 +247,7 @@ func (ctl *TermController) Update(c echo.Context) error {
 	}
 
 	record := TermModel{}
-	err = TermFindOne(id, &record)
+	err = TermFindOneContext(c.Request().Context(), id, &record)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"id":    id,
@@ -230,7 +260,7 @@ func (ctl *TermController) Update(c echo.Context) error {
 		}
 	}
 
-	record.LoadData()
+	record.LoadDataContext(c.Request().Context())
 
 	body := TermFindOneJSONResponse{Record: &record}
 
@@ -246,10 +276,22 @@ func (ctl *TermController) Update(c echo.Context) error {
 		return c.NoContent(http.StatusNotFound)
 	}
 
-	err = record.Save()
+	err = ctl.validateParent(c.Request().Context(), &record)
 	if err != nil {
 		return err
 	}
+
+	err = record.SaveContext(c.Request().Context())
+	if err != nil {
+		if errors.Is(err, ErrTermAliasConflict) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+
+	// the slug may change on rename
+	record.LoadTeaserData()
+
 	resp := TermFindOneJSONResponse{
 		Record: &record,
 	}
@@ -274,12 +316,12 @@ func (ctl *TermController) Delete(c echo.Context) error {
 	}
 
 	record := TermModel{}
-	err = TermFindOne(id, &record)
+	err = TermFindOneContext(c.Request().Context(), id, &record)
 	if err != nil {
 		return err
 	}
 
-	err = record.Delete()
+	err = record.DeleteContext(c.Request().Context())
 	if err != nil {
 		return err
 	}
@@ -287,51 +329,333 @@ func (ctl *TermController) Delete(c echo.Context) error {
 	return c.NoContent(http.StatusNoContent)
 }
 
+// Merge - Merge the :id term in the body targetId term, the :id term is removed and will redirect to the target
+func (ctl *TermController) Merge(c echo.Context) error {
+	id := c.Param("id")
+
+	ctx := c.(*catu.RequestContext)
+
+	can := ctx.Can("merge_term")
+	if !can {
+		return echo.NewHTTPError(http.StatusForbidden, "Forbidden")
+	}
+
+	var body TermMergeBodyRequest
+	if err := c.Bind(&body); err != nil {
+		if _, ok := err.(*echo.HTTPError); ok {
+			return err
+		}
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	if body.TargetID == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "targetId is required")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":       id,
+		"targetId": body.TargetID,
+	}).Info("TermController.Merge params")
+
+	source := TermModel{}
+	err := TermFindOneContext(c.Request().Context(), id, &source)
+	if err != nil || source.ID == 0 {
+		return &catu.HTTPError{
+			Code:    404,
+			Message: "not found",
+		}
+	}
+
+	target := TermModel{}
+	err = TermFindOneContext(c.Request().Context(), strconv.FormatUint(body.TargetID, 10), &target)
+	if err != nil || target.ID == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "target term not found")
+	}
+
+	err = TermMergeContext(c.Request().Context(), &source, &target)
+	if err != nil {
+		if errors.Is(err, ErrTermMergeSameTerm) || errors.Is(err, ErrTermMergeVocabulary) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return errors.Wrap(err, "TermController.Merge error on merge")
+	}
+
+	target.LoadDataContext(c.Request().Context())
+
+	resp := TermFindOneJSONResponse{
+		Record: &target,
+	}
+
+	return c.JSON(http.StatusOK, &resp)
+}
+
+// RecountUsage - Recount the usage of all vocabulary terms
+func (ctl *TermController) RecountUsage(c echo.Context) error {
+	ctx := c.(*catu.RequestContext)
+
+	can := ctx.Can("update_term")
+	if !can {
+		return echo.NewHTTPError(http.StatusForbidden, "Forbidden")
+	}
+
+	err := TermRecountUsageContext(c.Request().Context(), c.Param("vocabulary"))
+	if err != nil {
+		return errors.Wrap(err, "TermController.RecountUsage error on recount")
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Tree - Return all vocabulary terms as a tree
+func (ctl *TermController) Tree(c echo.Context) error {
+	vocabulary := c.Param("vocabulary")
+
+	records, err := TermFindTreeContext(c.Request().Context(), vocabulary)
+	if err != nil {
+		return errors.Wrap(err, "TermController.Tree error on find tree")
+	}
+
+	resp := TermTreeJSONResponse{
+		Records: records,
+	}
+
+	return c.JSON(http.StatusOK, &resp)
+}
+
+// Check the record parent before save, a zero parent id is handled as a root term
+func (ctl *TermController) validateParent(ctx context.Context, record *TermModel) error {
+	if record.ParentID == nil {
+		return nil
+	}
+
+	if *record.ParentID == 0 {
+		record.ParentID = nil
+		return nil
+	}
+
+	err := TermValidateParentContext(ctx, record, *record.ParentID)
+	if err != nil {
+		if errors.Is(err, ErrTermParentCycle) || errors.Is(err, ErrTermParentInvalid) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindAllPageHandler - Vocabulary page with the vocabulary terms list
 func (ctl *TermController) FindAllPageHandler(c echo.Context) error {
-	panic("TODO!")
+	ctx := c.(*catu.RequestContext)
+
+	// the vocabulary page is the concept scheme URI, the HTML, JSON and SKOS responses share the URL
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+
+	if contentType := skosContentTypeFromReq(c); contentType != "" {
+		return renderVocabularySKOS(c, c.Param("vocabulary"), contentType)
+	}
+
+	switch ctx.GetResponseContentType() {
+	case "application/json":
+		return ctl.Query(c)
+	}
+
+	if !isValidLocaleParam(c) {
+		return echo.NotFoundHandler(c)
+	}
+
+	vocabularyName := c.Param("vocabulary")
+	locale := GetRequestLocale(c)
+
+	logrus.WithFields(logrus.Fields{
+		"vocabulary": vocabularyName,
+		"locale":     locale,
+	}).Debug("TermController.FindAllPageHandler vocabulary from params")
+
+	// vocabularies like Tags may be used without a saved vocabulary record
+	vocabulary := VocabularyModel{}
+	err := VocabularyFindOneByNameContext(c.Request().Context(), vocabularyName, &vocabulary)
+	if err != nil {
+		return errors.Wrap(err, "TermController.FindAllPageHandler error on find vocabulary")
+	}
+
+	if vocabulary.ID == 0 {
+		vocabulary.Name = vocabularyName
+	}
+
+	vocabulary.LoadData()
+
+	var count int64
+	var records []TermModel
+	err = TermQueryAndCountReqContext(c.Request().Context(), &TermQueryOpts{
+		Records: &records,
+		Count:   &count,
+		Limit:   ctx.GetLimit(),
+		Offset:  ctx.GetOffset(),
+		C:       c,
+		IsHTML:  true,
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Debug("TermController.FindAllPageHandler Error on find terms")
+	}
+
+	if vocabulary.ID == 0 && count == 0 {
+		return echo.NotFoundHandler(c)
+	}
+
+	err = TermLoadManyLocalizedContext(c.Request().Context(), records, locale)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Debug("TermController.FindAllPageHandler Error on load translations")
+	}
+
+	ctx.Set("locale", locale)
+	ctx.Set("hasRecords", len(records) > 0)
+	ctx.Set("q", c.QueryParam("q"))
+	ctx.Set("RequestPath", ctx.Request().URL.String())
+
+	ctx.Title = vocabulary.Name
+	ctx.BodyClass = append(ctx.BodyClass, "body-term-findAll")
+
+	mt := c.Get("metatags").(*metatags.HTMLMetaTags)
+	mt.Title = vocabulary.Name
+	mt.Description = vocabulary.Description
+
+	ctx.Pager.Count = count
+
+	return c.Render(http.StatusOK, "taxonomy/term/findAll", &catu.TemplateCTX{
+		Ctx:     ctx,
+		Record:  &vocabulary,
+		Records: &records,
+	})
 }
 
 func (ctl *TermController) FindOnePageHandler(c echo.Context) error {
 	var err error
 	ctx := c.(*catu.RequestContext)
 
-	switch ctx.GetResponseContentType() {
-	case "application/json":
-		return ctl.FindOne(c)
+	if !isValidLocaleParam(c) {
+		return echo.NotFoundHandler(c)
 	}
 
-	id := c.Param("id")
+	termSlug := c.Param("slug")
 	vocabulary := c.Param("vocabulary")
+	locale := GetRequestLocale(c)
 
 	logrus.WithFields(logrus.Fields{
-		"id":         id,
+		"slug":       termSlug,
 		"vocabulary": vocabulary,
-	}).Debug("TermController.FindOnePagehandler id from params")
+		"locale":     locale,
+	}).Debug("TermController.FindOnePagehandler slug from params")
 
 	record := TermModel{}
 
-	err = TermFindOne(id, &record)
-	if err != nil {
-		return err
+	// numeric params are old term ids, see termFindMoved
+	if !termSlugIsNumeric(termSlug) {
+		err = TermFindOneBySlugContext(c.Request().Context(), termSlug, vocabulary, &record)
+		if err != nil {
+			return err
+		}
 	}
 
 	if record.ID == 0 {
+		// old numeric ids, old slugs and merged terms redirect to the current term url
+		target := TermModel{}
+		err = termFindMoved(c.Request().Context(), termSlug, vocabulary, &target)
+		if err != nil {
+			return err
+		}
+
+		if target.ID == 0 {
+			logrus.WithFields(logrus.Fields{
+				"slug": termSlug,
+			}).Debug("TermController.FindOnePagehandler slug record not found")
+			return echo.NotFoundHandler(c)
+		}
+
+		// terms saved before the slug support are still served by id
+		if target.Slug != "" {
+			return c.Redirect(http.StatusMovedPermanently, target.GetLocalizedPath(MatchAvailableLocale(c.Param("locale"))))
+		}
+
+		record = target
+	}
+
+	record.SetLocale(locale)
+	record.LoadDataContext(c.Request().Context())
+
+	switch ctx.GetResponseContentType() {
+	case "application/json":
+		return c.JSON(http.StatusOK, &TermFindOneJSONResponse{Record: &record})
+	}
+
+	var ancestors []TermModel
+	err = TermFindAncestorsContext(c.Request().Context(), &record, &ancestors)
+	if err != nil {
 		logrus.WithFields(logrus.Fields{
-			"id": id,
-		}).Debug("TermController.FindOnePagehandler id record not found")
-		return echo.NotFoundHandler(c)
+			"error": err,
+		}).Debug("FindOnePageHandler Error on find term ancestors")
+	}
+
+	var children []TermModel
+	err = TermFindChildrenContext(c.Request().Context(), record.GetIDString(), &children)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Debug("FindOnePageHandler Error on find term children")
+	}
+
+	err = TermLoadManyLocalizedContext(c.Request().Context(), ancestors, locale)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Debug("FindOnePageHandler Error on load ancestors translations")
+	}
+
+	err = TermLoadManyLocalizedContext(c.Request().Context(), children, locale)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Debug("FindOnePageHandler Error on load children translations")
+	}
+
+	var relatedTerms []TermRelatedItem
+	err = TermFindRelatedContext(c.Request().Context(), &TermRelatedOpts{
+		TermID:         record.ID,
+		VocabularyName: record.VocabularyName,
+		Locale:         locale,
+	}, &relatedTerms)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Debug("FindOnePageHandler Error on find related terms")
 	}
 
-	record.LoadData()
+	// only list records from the registered fields of this vocabulary, all fields if none is registered
+	var fields []FieldConfigurationInterface
+	if p := GetPluginFromApp(ctl.App); p != nil {
+		fields = p.GetVocabularyFields(record.VocabularyName)
+	}
+
+	expr, err := TermExpressionFromReq(c)
+	if err != nil {
+		return err
+	}
 
 	var count int64
 	var records []ModelstermsModel
-	err = ModelstermQueryAndCountReq(&ModelstermQueryOpts{
-		Records: &records,
-		Count:   &count,
-		Limit:   ctx.GetLimit(),
-		Offset:  ctx.GetOffset(),
-		C:       c,
+	err = ModelstermQueryAndCountReqContext(c.Request().Context(), &ModelstermQueryOpts{
+		Records:        &records,
+		Count:          &count,
+		Limit:          ctx.GetLimit(),
+		Offset:         ctx.GetOffset(),
+		C:              c,
+		TermID:         record.GetIDString(),
+		Fields:         fields,
+		TermExpression: expr,
 	})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
@@ -355,19 +679,46 @@ func (ctl *TermController) FindOnePageHandler(c echo.Context) error {
 		}
 	}
 
+	ctx.Set("ancestors", ancestors)
+	ctx.Set("children", children)
+	ctx.Set("relatedTerms", relatedTerms)
+	ctx.Set("tagq", c.QueryParam("tagq"))
+	ctx.Set("fields", fields)
 	ctx.Set("hasRecords", hasRecords)
 	ctx.Set("records", teaserList)
 	ctx.Set("RequestPath", ctx.Request().URL.String())
 
-	ctx.Title = record.Text
-	ctx.BodyClass = append(ctx.BodyClass, "body-content-findOne")
+	ctx.Set("locale", locale)
+	ctx.Set("localizedText", record.GetLocalizedText(locale))
+	ctx.Set("localizedDescription", record.GetLocalizedDescription(locale))
 
-	mt := c.Get("metatags").(*metatags.HTMLMetaTags)
-	mt.Title = record.Text
-	mt.Description = record.Description
+	ctx.Title = record.GetLocalizedText(locale)
+	ctx.BodyClass = append(ctx.BodyClass, "body-content-findOne")
 
 	ctx.Pager.Count = count
 
+	// vocabularies like Tags may be used without a saved vocabulary record
+	vocabularyRecord := VocabularyModel{}
+	err = VocabularyFindOneByNameContext(c.Request().Context(), record.VocabularyName, &vocabularyRecord)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Debug("FindOnePageHandler Error on find vocabulary")
+	}
+	vocabularyRecord.Name = record.VocabularyName
+
+	seo := NewTermSEO(&record, &vocabularyRecord, locale, ctx.Pager)
+	if seo.NoIndex {
+		c.Response().Header().Set("X-Robots-Tag", "noindex")
+	}
+
+	ctx.Set("seo", seo)
+	ctx.Set("seoHead", seo.HeadHTML())
+
+	mt := c.Get("metatags").(*metatags.HTMLMetaTags)
+	mt.Title = seo.Title
+	mt.Description = seo.Description
+
 	return c.Render(http.StatusOK, "taxonomy/term/findOne", &catu.TemplateCTX{
 		Ctx:     ctx,
 		Record:  &record,
@@ -375,6 +726,41 @@ func (ctl *TermController) FindOnePageHandler(c echo.Context) error {
 	})
 }
 
+// Find the current term for one old term url param: numeric id, merged term id or old slug.
+// Numeric params are ids first because the term urls used ids before the slug support
+func termFindMoved(ctx context.Context, param, vocabularyName string, target *TermModel) error {
+	if _, err := strconv.ParseUint(param, 10, 64); err != nil {
+		return TermFindOneByOldSlugContext(ctx, param, vocabularyName, target)
+	}
+
+	err := TermFindOneContext(ctx, param, target)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if target.ID != 0 {
+		return nil
+	}
+
+	redirect := TermRedirectModel{}
+	err = TermRedirectFindOneContext(ctx, param, &redirect)
+	if err != nil {
+		return err
+	}
+
+	if redirect.ID == 0 {
+		// all-digit slugs saved before they got one prefix
+		return TermFindOneByOldSlugContext(ctx, param, vocabularyName, target)
+	}
+
+	err = TermFindOneContext(ctx, strconv.FormatUint(redirect.TargetID, 10), target)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return nil
+}
+
 func (ctl *TermController) TermTexts(c echo.Context) error {
 	var err error
 	ctx := c.(*catu.RequestContext)
@@ -393,8 +779,161 @@ func (ctl *TermController) TermTexts(c echo.Context) error {
 	return c.JSON(200, res)
 }
 
+type TermSearchJSONResponse struct {
+	catu.BaseListReponse
+	Records []TermSearchResult `json:"term"`
+}
+
+// Search - Full-text search in the vocabulary terms text and description, sorted by relevance.
+// Query params: q, limit and offset
+func (ctl *TermController) Search(c echo.Context) error {
+	ctx := c.(*catu.RequestContext)
+
+	var count int64
+	records := []TermSearchResult{}
+
+	err := TermSearchContext(c.Request().Context(), &TermSearchOpts{
+		VocabularyName: c.Param("vocabulary"),
+		Q:              c.QueryParam("q"),
+		Limit:          ctx.GetLimit(),
+		Offset:         ctx.GetOffset(),
+	}, &records, &count)
+	if err != nil {
+		return errors.Wrap(err, "TermController.Search error on search")
+	}
+
+	ctx.Pager.Count = count
+
+	resp := TermSearchJSONResponse{
+		Records: records,
+	}
+
+	resp.Meta.Count = count
+
+	return c.JSON(http.StatusOK, &resp)
+}
+
+type TermAutocompleteJSONResponse struct {
+	Records []TermAutocompleteItem `json:"term"`
+}
+
+type TermRelatedJSONResponse struct {
+	catu.BaseListReponse
+	Records []TermRelatedItem `json:"relatedTerm"`
+}
+
+// Related - Return the vocabulary terms most often used in the same records of the :id term.
+// Query params: modelName and limit (max TermRelatedMaxLimit)
+func (ctl *TermController) Related(c echo.Context) error {
+	id := c.Param("id")
+	vocabulary := c.Param("vocabulary")
+
+	record := TermModel{}
+	err := TermFindOneContext(c.Request().Context(), id, &record)
+	if err != nil {
+		return err
+	}
+
+	if record.ID == 0 || record.VocabularyName != vocabulary {
+		return &catu.HTTPError{
+			Code:    404,
+			Message: "not found",
+		}
+	}
+
+	records := []TermRelatedItem{}
+	err = TermFindRelatedContext(c.Request().Context(), &TermRelatedOpts{
+		TermID:         record.ID,
+		VocabularyName: vocabulary,
+		ModelName:      c.QueryParam("modelName"),
+		Limit:          catu.GetQueryIntFromReq("limit", c),
+	}, &records)
+	if err != nil {
+		return errors.Wrap(err, "TermController.Related error on find related terms")
+	}
+
+	resp := TermRelatedJSONResponse{
+		Records: records,
+	}
+
+	resp.Meta.Count = int64(len(records))
+
+	return c.JSON(http.StatusOK, &resp)
+}
+
+// Autocomplete - Return vocabulary terms matching the q query param, accents and case are ignored.
+// Query params: q and limit (max TermAutocompleteMaxLimit)
+func (ctl *TermController) Autocomplete(c echo.Context) error {
+	records := []TermAutocompleteItem{}
+
+	err := TermAutocompleteContext(c.Request().Context(), &TermAutocompleteOpts{
+		VocabularyName: c.Param("vocabulary"),
+		Q:              c.QueryParam("q"),
+		Limit:          catu.GetQueryIntFromReq("limit", c),
+	}, &records)
+	if err != nil {
+		return errors.Wrap(err, "TermController.Autocomplete error on find terms")
+	}
+
+	return c.JSON(http.StatusOK, &TermAutocompleteJSONResponse{Records: records})
+}
+
+type TagCloudJSONResponse struct {
+	catu.BaseListReponse
+	Records *[]TagCloudItem `json:"tagCloud"`
+}
+
+// TagClound - Return the most used vocabulary terms with their weight class
+// Query params: modelName, field, since, until (RFC3339 or YYYY-MM-DD), weights and limit
 func (ctl *TermController) TagClound(c echo.Context) error {
-	panic("TODO!")
+	ctx := c.(*catu.RequestContext)
+
+	opts := TagCloudOpts{
+		VocabularyName: c.Param("vocabulary"),
+		ModelName:      c.QueryParam("modelName"),
+		Field:          c.QueryParam("field"),
+		Limit:          ctx.GetLimit(),
+		Weights:        catu.GetQueryIntFromReq("weights", c),
+	}
+
+	if since := c.QueryParam("since"); since != "" {
+		t, err := parseQueryTime(since)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid since param")
+		}
+		opts.Since = &t
+	}
+
+	if until := c.QueryParam("until"); until != "" {
+		t, err := parseQueryTime(until)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid until param")
+		}
+		opts.Until = &t
+	}
+
+	records := []TagCloudItem{}
+	err := TagCloudFindContext(c.Request().Context(), &opts, &records)
+	if err != nil {
+		return errors.Wrap(err, "TermController.TagClound error on find tag cloud")
+	}
+
+	resp := TagCloudJSONResponse{
+		Records: &records,
+	}
+
+	resp.Meta.Count = int64(len(records))
+
+	return c.JSON(http.StatusOK, &resp)
+}
+
+func parseQueryTime(v string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, v)
+	if err == nil {
+		return t, nil
+	}
+
+	return time.Parse("2006-01-02", v)
 }
 
 type TermControllerCfg struct {
@@ -0,0 +1,439 @@
+package tags
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Max terms in one tag expression, each term is one subquery
+const TermExpressionMaxTerms = 50
+
+// Max nested NOT and parentheses in one tag expression
+const TermExpressionMaxDepth = 32
+
+// TermExpressionError - Tag expression parse error, Pos is the 1-based character position in the expression
+type TermExpressionError struct {
+	Pos     int
+	Message string
+}
+
+func (e *TermExpressionError) Error() string {
+	return fmt.Sprintf("tag expression error at position %d: %s", e.Pos, e.Message)
+}
+
+// TermExpression - Parsed boolean tag expression, ex: (go OR rust) AND backend AND NOT "web design"
+type TermExpression struct {
+	root termExprNode
+}
+
+type termExprNode interface {
+	String() string
+	// Build the SQL condition matching the target record
+	where(db *gorm.DB, target *termExprTarget) (string, []interface{})
+}
+
+type termExprTarget struct {
+	ModelID        clause.Column
+	ModelName      interface{}
+	Field          string
+	VocabularyName string
+}
+
+type termExprTerm struct {
+	VocabularyName string
+	Text           string
+}
+
+type termExprAnd struct{ Left, Right termExprNode }
+type termExprOr struct{ Left, Right termExprNode }
+type termExprNot struct{ Expr termExprNode }
+
+func (n *termExprTerm) String() string {
+	text := quoteTermExprText(n.Text)
+	if n.VocabularyName != "" {
+		return quoteTermExprText(n.VocabularyName) + ":" + text
+	}
+	return text
+}
+
+func (n *termExprAnd) String() string {
+	return "(" + n.Left.String() + " AND " + n.Right.String() + ")"
+}
+func (n *termExprOr) String() string  { return "(" + n.Left.String() + " OR " + n.Right.String() + ")" }
+func (n *termExprNot) String() string { return "NOT " + n.Expr.String() }
+
+func (n *termExprTerm) where(db *gorm.DB, target *termExprTarget) (string, []interface{}) {
+	vocabularyName := n.VocabularyName
+	if vocabularyName == "" {
+		vocabularyName = target.VocabularyName
+	}
+
+	subquery := termScopeSubquery(db, target.ModelID, target.ModelName, target.Field, vocabularyName, []interface{}{n.Text})
+	return "EXISTS (?)", []interface{}{subquery}
+}
+
+func (n *termExprAnd) where(db *gorm.DB, target *termExprTarget) (string, []interface{}) {
+	left, leftVars := n.Left.where(db, target)
+	right, rightVars := n.Right.where(db, target)
+	return "(" + left + " AND " + right + ")", append(leftVars, rightVars...)
+}
+
+func (n *termExprOr) where(db *gorm.DB, target *termExprTarget) (string, []interface{}) {
+	left, leftVars := n.Left.where(db, target)
+	right, rightVars := n.Right.where(db, target)
+	return "(" + left + " OR " + right + ")", append(leftVars, rightVars...)
+}
+
+func (n *termExprNot) where(db *gorm.DB, target *termExprTarget) (string, []interface{}) {
+	expr, vars := n.Expr.where(db, target)
+	return "NOT " + expr, vars
+}
+
+// Quote texts that would not be parsed back as one term
+func quoteTermExprText(text string) string {
+	switch strings.ToUpper(text) {
+	case "AND", "OR", "NOT":
+		return `"` + text + `"`
+	}
+
+	if text != "" && strings.IndexFunc(text, func(r rune) bool { return !isTermExprWordRune(r) }) == -1 {
+		return text
+	}
+
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+}
+
+// String - Normalized expression with explicit operators and parentheses
+func (e *TermExpression) String() string {
+	return e.root.String()
+}
+
+// Scope - GORM scope to filter the query model records matching the expression, see HasTerms.
+// Terms without the vocabulary: prefix are searched in vocabularyName, or in all vocabularies if it is empty
+func (e *TermExpression) Scope(modelName, field, vocabularyName string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		sql, vars := e.root.where(db, &termExprTarget{
+			ModelID:        termScopeModelID(db),
+			ModelName:      modelName,
+			Field:          field,
+			VocabularyName: vocabularyName,
+		})
+		return db.Where(sql, vars...)
+	}
+}
+
+// Filter modelsterms rows whose record matches the expression, used in queries over the modelsterms table
+func (e *TermExpression) whereModelsterms(db *gorm.DB, table, vocabularyName string) *gorm.DB {
+	sql, vars := e.root.where(db, &termExprTarget{
+		ModelID:        clause.Column{Table: table, Name: "modelId"},
+		ModelName:      clause.Column{Table: table, Name: "modelName"},
+		VocabularyName: vocabularyName,
+	})
+	return db.Where(sql, vars...)
+}
+
+// ParseTermExpression - Parse one boolean tag expression.
+// Supports AND, OR and NOT (case insensitive), parentheses, "quoted multi word" terms and vocabulary:term
+// prefixes. Terms separated only by spaces are joined with AND. Errors are *TermExpressionError
+func ParseTermExpression(expression string) (*TermExpression, error) {
+	tokens, err := lexTermExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := termExprParser{tokens: tokens}
+	if p.peek().kind == termExprTokenEOF {
+		return nil, &TermExpressionError{Pos: 1, Message: "empty expression"}
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != termExprTokenEOF {
+		if t.kind == termExprTokenRParen {
+			return nil, &TermExpressionError{Pos: t.pos, Message: "unexpected \")\" without matching \"(\""}
+		}
+		return nil, &TermExpressionError{Pos: t.pos, Message: fmt.Sprintf("unexpected %s", t)}
+	}
+
+	if p.terms > TermExpressionMaxTerms {
+		return nil, &TermExpressionError{
+			Pos:     1,
+			Message: fmt.Sprintf("too many terms, max %d", TermExpressionMaxTerms),
+		}
+	}
+
+	return &TermExpression{root: root}, nil
+}
+
+// Parse the tagq query param, returns nil without the param and one 400 HTTP error on parse errors
+func TermExpressionFromReq(c echo.Context) (*TermExpression, error) {
+	tagq := strings.TrimSpace(c.QueryParam("tagq"))
+	if tagq == "" {
+		return nil, nil
+	}
+
+	expr, err := ParseTermExpression(tagq)
+	if err != nil {
+		return nil, echo.NewHTTPError(400, "tagq: "+err.Error())
+	}
+
+	return expr, nil
+}
+
+type termExprTokenKind int
+
+const (
+	termExprTokenEOF termExprTokenKind = iota
+	termExprTokenTerm
+	termExprTokenAnd
+	termExprTokenOr
+	termExprTokenNot
+	termExprTokenLParen
+	termExprTokenRParen
+)
+
+type termExprToken struct {
+	kind           termExprTokenKind
+	pos            int
+	vocabularyName string
+	text           string
+}
+
+func (t termExprToken) String() string {
+	switch t.kind {
+	case termExprTokenEOF:
+		return "end of expression"
+	case termExprTokenTerm:
+		return fmt.Sprintf("term %q", t.text)
+	case termExprTokenAnd:
+		return "AND"
+	case termExprTokenOr:
+		return "OR"
+	case termExprTokenNot:
+		return "NOT"
+	case termExprTokenLParen:
+		return "\"(\""
+	default:
+		return "\")\""
+	}
+}
+
+func isTermExprWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && r != '(' && r != ')' && r != '"' && r != ':'
+}
+
+func lexTermExpression(expression string) ([]termExprToken, error) {
+	runes := []rune(expression)
+	tokens := []termExprToken{}
+
+	// read one bare or quoted segment starting at i, returns the text, if it was quoted and the next index
+	segment := func(i int) (string, bool, int, error) {
+		if runes[i] != '"' {
+			start := i
+			for i < len(runes) && isTermExprWordRune(runes[i]) {
+				i++
+			}
+			return string(runes[start:i]), false, i, nil
+		}
+
+		var b strings.Builder
+		for j := i + 1; j < len(runes); j++ {
+			switch runes[j] {
+			case '\\':
+				if j+1 < len(runes) {
+					j++
+					b.WriteRune(runes[j])
+				}
+			case '"':
+				text := strings.TrimSpace(b.String())
+				if text == "" {
+					return "", true, j + 1, &TermExpressionError{Pos: i + 1, Message: "empty quoted term"}
+				}
+				return text, true, j + 1, nil
+			default:
+				b.WriteRune(runes[j])
+			}
+		}
+
+		return "", true, len(runes), &TermExpressionError{Pos: i + 1, Message: "unterminated quoted term"}
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, termExprToken{kind: termExprTokenLParen, pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, termExprToken{kind: termExprTokenRParen, pos: pos})
+			i++
+		case r == ':':
+			return nil, &TermExpressionError{Pos: pos, Message: "missing vocabulary before \":\""}
+		default:
+			text, quoted, next, err := segment(i)
+			if err != nil {
+				return nil, err
+			}
+			i = next
+
+			if i < len(runes) && runes[i] == ':' {
+				i++
+				if i >= len(runes) || (!isTermExprWordRune(runes[i]) && runes[i] != '"') {
+					return nil, &TermExpressionError{Pos: i + 1, Message: fmt.Sprintf("missing term after %q", text+":")}
+				}
+
+				term, _, next, err := segment(i)
+				if err != nil {
+					return nil, err
+				}
+				i = next
+
+				tokens = append(tokens, termExprToken{kind: termExprTokenTerm, pos: pos, vocabularyName: text, text: term})
+				continue
+			}
+
+			kind := termExprTokenTerm
+			if !quoted {
+				switch strings.ToUpper(text) {
+				case "AND":
+					kind = termExprTokenAnd
+				case "OR":
+					kind = termExprTokenOr
+				case "NOT":
+					kind = termExprTokenNot
+				}
+			}
+
+			tokens = append(tokens, termExprToken{kind: kind, pos: pos, text: text})
+		}
+	}
+
+	return append(tokens, termExprToken{kind: termExprTokenEOF, pos: len(runes) + 1}), nil
+}
+
+type termExprParser struct {
+	tokens []termExprToken
+	i      int
+	terms  int
+	depth  int
+}
+
+func (p *termExprParser) peek() termExprToken {
+	return p.tokens[p.i]
+}
+
+func (p *termExprParser) next() termExprToken {
+	t := p.tokens[p.i]
+	if t.kind != termExprTokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// or := and (OR and)*
+func (p *termExprParser) parseOr() (termExprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == termExprTokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &termExprOr{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+// and := unary ([AND] unary)*
+func (p *termExprParser) parseAnd() (termExprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch p.peek().kind {
+		case termExprTokenAnd:
+			p.next()
+		case termExprTokenTerm, termExprTokenNot, termExprTokenLParen:
+		default:
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &termExprAnd{Left: left, Right: right}
+	}
+}
+
+// Increase the nesting depth of NOT and "(" tokens, the expression is in the public tagq param
+func (p *termExprParser) enter(t termExprToken) error {
+	p.depth++
+	if p.depth > TermExpressionMaxDepth {
+		return &TermExpressionError{
+			Pos:     t.pos,
+			Message: fmt.Sprintf("expression nested too deep, max %d", TermExpressionMaxDepth),
+		}
+	}
+
+	return nil
+}
+
+// unary := NOT unary | term | "(" or ")"
+func (p *termExprParser) parseUnary() (termExprNode, error) {
+	t := p.next()
+
+	switch t.kind {
+	case termExprTokenNot, termExprTokenLParen:
+		err := p.enter(t)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+	}
+
+	switch t.kind {
+	case termExprTokenNot:
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &termExprNot{Expr: expr}, nil
+	case termExprTokenTerm:
+		p.terms++
+		return &termExprTerm{VocabularyName: t.vocabularyName, Text: t.text}, nil
+	case termExprTokenLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != termExprTokenRParen {
+			return nil, &TermExpressionError{
+				Pos:     t.pos,
+				Message: fmt.Sprintf("missing \")\" for \"(\", found %s at position %d", closing, closing.pos),
+			}
+		}
+		return expr, nil
+	default:
+		return nil, &TermExpressionError{Pos: t.pos, Message: fmt.Sprintf("expected term, found %s", t)}
+	}
+}
@@ -0,0 +1,95 @@
+package tags
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTermExpression(t *testing.T) {
+	assert := assert.New(t)
+
+	valid := map[string]string{
+		"go":      "go",
+		"go rust": "(go AND rust)",
+		"(go OR rust) AND backend AND NOT deprecated": "(((go OR rust) AND backend) AND NOT deprecated)",
+		"go or rust and web":                          "(go OR (rust AND web))",
+		`"web design" AND Tags:"and"`:                 `("web design" AND Tags:"and")`,
+		`"Main Topics":go NOT (a OR b)`:               `("Main Topics":go AND NOT (a OR b))`,
+	}
+
+	// max nesting
+	valid[strings.Repeat("(", 32)+"go"+strings.Repeat(")", 32)] = "go"
+
+	for expression, expected := range valid {
+		expr, err := ParseTermExpression(expression)
+		assert.Nil(err, expression)
+		if err == nil {
+			assert.Equal(expected, expr.String(), expression)
+		}
+	}
+
+	invalid := map[string]int{
+		"":              1,
+		"go AND":        7,
+		"(go OR rust":   1,
+		"go) rust":      3,
+		`go "web`:       4,
+		"Tags: go":      6,
+		":go":           1,
+		"go OR OR rust": 7,
+	}
+
+	// too deep, the position of the first NOT or "(" after the max depth
+	invalid[strings.Repeat("NOT ", 33)+"go"] = 129
+	invalid[strings.Repeat("(", 33)+"go"+strings.Repeat(")", 33)] = 33
+	invalid[strings.Repeat("NOT (", 1000)+"go"] = 81
+
+	for expression, pos := range invalid {
+		_, err := ParseTermExpression(expression)
+		exprErr := &TermExpressionError{}
+		if assert.True(errors.As(err, &exprErr), expression) {
+			assert.Equal(pos, exprErr.Pos, expression)
+		}
+	}
+}
+
+func TestTermExpressionScope(t *testing.T) {
+	assert := assert.New(t)
+
+	app := GetAppInstance()
+	db := app.GetDB()
+
+	records := []TaxonomyContentStub{{Title: "A"}, {Title: "B"}, {Title: "C"}}
+	assert.Nil(db.Create(&records).Error)
+
+	cfg := NewTagFieldConfiguration("tagqtags", "taxonomy_content_stubs", "tagqtags")
+	assert.Nil(cfg.Update(records[0].GetIDString(), []string{"go", "backend"}))
+	assert.Nil(cfg.Update(records[1].GetIDString(), []string{"rust", "backend", "deprecated"}))
+	assert.Nil(cfg.Update(records[2].GetIDString(), []string{"web design"}))
+
+	find := func(expression string) []string {
+		expr, err := ParseTermExpression(expression)
+		assert.Nil(err)
+
+		list := []TaxonomyContentStub{}
+		err = db.Scopes(expr.Scope("taxonomy_content_stubs", "", "tagqtags")).
+			Where("id IN ?", []uint64{records[0].ID, records[1].ID, records[2].ID}).
+			Order("id ASC").
+			Find(&list).Error
+		assert.Nil(err)
+
+		titles := []string{}
+		for _, r := range list {
+			titles = append(titles, r.Title)
+		}
+		return titles
+	}
+
+	assert.Equal([]string{"A"}, find("(go OR rust) AND backend AND NOT deprecated"))
+	assert.Equal([]string{"A", "B"}, find("backend"))
+	assert.Equal([]string{"C"}, find(`tagqtags:"web design" OR unknown`))
+	assert.Equal([]string{}, find("othervocabulary:go"))
+}
@@ -0,0 +1,303 @@
+package tags
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-catupiry/catu"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Default and max number of items in one term feed
+const (
+	TermFeedDefaultLimit = 20
+	TermFeedMaxLimit     = 50
+)
+
+// FeedItem - One term feed item, returned by the Plugin.RenderFeedItem hook for the associated record
+type FeedItem struct {
+	Title   string
+	Link    string
+	Summary string
+	// Defaults to the association creation date
+	Date time.Time
+
+	// Associated record path used in the item id if the item has no link, ex: /content/1
+	recordPath string
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	Description string  `xml:"description,omitempty"`
+	PubDate     string  `xml:"pubDate"`
+	GUID        rssGUID `xml:"guid"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string     `xml:"title"`
+	ID      string     `xml:"id"`
+	Updated string     `xml:"updated"`
+	Links   []atomLink `xml:"link"`
+	Summary string     `xml:"summary,omitempty"`
+}
+
+// Find the latest associations of the term or its descendants in the vocabulary fields, newest first
+func (r *Plugin) TermFeedFindAssociations(term *TermModel, limit int, records *[]ModelstermsModel) error {
+	return r.TermFeedFindAssociationsContext(context.Background(), term, limit, records)
+}
+
+// TermFeedFindAssociationsContext - TermFeedFindAssociations using ctx in the database queries
+func (r *Plugin) TermFeedFindAssociationsContext(ctx context.Context, term *TermModel, limit int, records *[]ModelstermsModel) error {
+	if limit <= 0 {
+		limit = TermFeedDefaultLimit
+	}
+	if limit > TermFeedMaxLimit {
+		limit = TermFeedMaxLimit
+	}
+
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+	fields := r.GetVocabularyFields(term.VocabularyName)
+
+	query, err := modelstermsWhereTermSubtree(db, db.Model(&ModelstermsModel{}), term.GetIDString(), fields)
+	if err != nil {
+		return err
+	}
+
+	err = modelstermsWhereFields(query, fields).
+		Order("createdAt DESC").
+		Order("id DESC").
+		Limit(limit).
+		Find(records).Error
+	if err != nil {
+		return errors.Wrap(err, "TermFeedFindAssociations error on find associations")
+	}
+
+	return nil
+}
+
+// Render the latest associations of the term as feed items with the RenderFeedItem hook, records without
+// item are skipped
+func (r *Plugin) TermFeedItems(ctx *catu.RequestContext, term *TermModel, limit int) ([]FeedItem, error) {
+	records := []ModelstermsModel{}
+	err := r.TermFeedFindAssociationsContext(ctx.Request().Context(), term, limit, &records)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []FeedItem{}
+	for i := range records {
+		item, err := r.RenderFeedItem(&records[i], ctx)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"modelName": records[i].ModelName,
+				"modelId":   records[i].ModelID,
+				"error":     err,
+			}).Error("TermFeedItems error on render feed item")
+			continue
+		}
+
+		if item == nil {
+			continue
+		}
+
+		if item.Date.IsZero() {
+			item.Date = records[i].CreatedAt
+		}
+
+		item.recordPath = "/" + records[i].ModelName + "/" + strconv.FormatUint(records[i].ModelID, 10)
+
+		items = append(items, *item)
+	}
+
+	return items, nil
+}
+
+// Feed - RSS 2.0 or Atom feed with the latest records of one term, the :slug param also accepts old slugs and
+// ids. Query params: format (rss or atom, defaults to the Accept header or rss) and limit
+func (ctl *TermController) Feed(c echo.Context) error {
+	ctx := c.(*catu.RequestContext)
+
+	p := GetPluginFromApp(ctl.App)
+	if p == nil {
+		return echo.NotFoundHandler(c)
+	}
+
+	param := c.Param("slug")
+	vocabulary := c.Param("vocabulary")
+
+	record := TermModel{}
+	if !termSlugIsNumeric(param) {
+		err := TermFindOneBySlugContext(c.Request().Context(), param, vocabulary, &record)
+		if err != nil {
+			return err
+		}
+	}
+
+	if record.ID == 0 {
+		err := termFindMoved(c.Request().Context(), param, vocabulary, &record)
+		if err != nil {
+			return err
+		}
+
+		if record.ID == 0 || record.VocabularyName != vocabulary {
+			return echo.NotFoundHandler(c)
+		}
+	}
+
+	locale := GetRequestLocale(c)
+	record.SetLocale(locale)
+	record.LoadDataContext(c.Request().Context())
+
+	items, err := p.TermFeedItems(ctx, &record, catu.GetQueryIntFromReq("limit", c))
+	if err != nil {
+		return errors.Wrap(err, "TermController.Feed error on find items")
+	}
+
+	updated := record.UpdatedAt
+	for _, item := range items {
+		if item.Date.After(updated) {
+			updated = item.Date
+		}
+	}
+
+	title := record.GetLocalizedText(locale)
+	selfURL := catu.GetConfiguration().Get("APP_ORIGIN") + c.Request().URL.Path
+	host := termFeedHost(c)
+
+	if termFeedFormatFromReq(c) == "atom" {
+		feed := atomFeed{
+			Title:   title,
+			ID:      record.LinkPermanent,
+			Updated: updated.UTC().Format(time.RFC3339),
+			Links: []atomLink{
+				{Href: record.LinkPermanent, Rel: "alternate"},
+				{Href: selfURL, Rel: "self"},
+			},
+			Entries: []atomEntry{},
+		}
+
+		for _, item := range items {
+			feed.Entries = append(feed.Entries, atomEntry{
+				Title:   item.Title,
+				ID:      termFeedItemID(item, host),
+				Updated: item.Date.UTC().Format(time.RFC3339),
+				Links:   []atomLink{{Href: item.Link, Rel: "alternate"}},
+				Summary: item.Summary,
+			})
+		}
+
+		return termFeedRender(c, "application/atom+xml; charset=UTF-8", feed)
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         title,
+			Link:          record.LinkPermanent,
+			Description:   record.GetLocalizedDescription(locale),
+			LastBuildDate: updated.UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	if feed.Channel.Description == "" {
+		feed.Channel.Description = title
+	}
+
+	for _, item := range items {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Summary,
+			PubDate:     item.Date.UTC().Format(time.RFC1123Z),
+			GUID:        rssGUID{Value: termFeedItemID(item, host), IsPermaLink: item.Link != ""},
+		})
+	}
+
+	return termFeedRender(c, "application/rss+xml; charset=UTF-8", feed)
+}
+
+// Items without link use one tag URI built from the title and date
+func termFeedItemID(item FeedItem, host string) string {
+	if item.Link != "" {
+		return item.Link
+	}
+
+	// tag URI, see RFC 4151, ex: tag:example.com,2023-01-02:/content/1
+	return "tag:" + host + "," + item.Date.UTC().Format("2006-01-02") + ":" + item.recordPath
+}
+
+// Get the host name of the APP_ORIGIN configuration or the request, used in the feed item ids
+func termFeedHost(c echo.Context) string {
+	u, err := url.Parse(catu.GetConfiguration().Get("APP_ORIGIN"))
+	if err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+
+	u = &url.URL{Host: c.Request().Host}
+	return u.Hostname()
+}
+
+// Get the feed format from the format query param or the Accept header, rss or atom
+func termFeedFormatFromReq(c echo.Context) string {
+	switch c.QueryParam("format") {
+	case "atom":
+		return "atom"
+	case "rss":
+		return "rss"
+	}
+
+	if c.Request().Header.Get(echo.HeaderAccept) == "application/atom+xml" {
+		return "atom"
+	}
+
+	return "rss"
+}
+
+func termFeedRender(c echo.Context, contentType string, feed interface{}) error {
+	body, err := xml.Marshal(feed)
+	if err != nil {
+		return errors.Wrap(err, "termFeedRender error on marshal xml")
+	}
+
+	return c.Blob(http.StatusOK, contentType, append([]byte(xml.Header), body...))
+}
@@ -0,0 +1,69 @@
+package tags
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTermFeedFindAssociations(t *testing.T) {
+	assert := assert.New(t)
+	app := GetAppInstance()
+	db := app.GetDB()
+	p := NewPlugin(&PluginCfgs{})
+
+	parent := TermModel{Text: "feedparent", VocabularyName: "feedcategory"}
+	assert.Nil(parent.Save())
+	child := TermModel{Text: "feedchild", VocabularyName: "feedcategory", ParentID: &parent.ID}
+	assert.Nil(child.Save())
+
+	now := time.Now()
+	for i, termID := range []uint64{parent.ID, child.ID, parent.ID} {
+		assoc, _ := NewModelsterms("feedcategory", "feedpost", "category", uint64(i+1), termID)
+		assoc.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+		assert.Nil(db.Create(&assoc).Error)
+	}
+
+	// one record with the child term in two fields
+	for _, field := range []string{"category", "featured"} {
+		assoc, _ := NewModelsterms("feedcategory", "feedpost", field, 4, child.ID)
+		assoc.CreatedAt = now.Add(-time.Minute)
+		assert.Nil(db.Create(&assoc).Error)
+	}
+
+	records := []ModelstermsModel{}
+	assert.Nil(p.TermFeedFindAssociations(&parent, 0, &records))
+	assert.Equal(4, len(records))
+	assert.Equal(uint64(3), records[0].ModelID)
+	assert.Equal(uint64(2), records[1].ModelID)
+	assert.Equal(uint64(1), records[2].ModelID)
+	assert.Equal(uint64(4), records[3].ModelID)
+
+	records = []ModelstermsModel{}
+	assert.Nil(p.TermFeedFindAssociations(&parent, 1, &records))
+	assert.Equal(1, len(records))
+
+	records = []ModelstermsModel{}
+	assert.Nil(p.TermFeedFindAssociations(&child, 0, &records))
+	assert.Equal(2, len(records))
+	assert.Equal(uint64(2), records[0].ModelID)
+	assert.Equal(uint64(4), records[1].ModelID)
+}
+
+func TestTermFeedItemID(t *testing.T) {
+	assert := assert.New(t)
+	GetAppInstance()
+
+	date := time.Date(2023, 1, 2, 10, 0, 0, 0, time.UTC)
+	assert.Equal("https://example.com/content/1", termFeedItemID(FeedItem{Link: "https://example.com/content/1", Date: date}, "example.com"))
+	assert.Equal("tag:example.com,2023-01-02:/content/1", termFeedItemID(FeedItem{Title: "Hello", Date: date, recordPath: "/content/1"}, "example.com"))
+
+	t.Setenv("APP_ORIGIN", "https://example.com:8080")
+	assert.Equal("example.com", termFeedHost(newTestRequestContext("/vocabulary/Tags/term/go/feed")))
+
+	t.Setenv("APP_ORIGIN", "")
+	c := newTestRequestContext("/vocabulary/Tags/term/go/feed")
+	c.Request().Host = "localhost:8080"
+	assert.Equal("localhost", termFeedHost(c))
+}
@@ -0,0 +1,266 @@
+package tags
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-catupiry/catu"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var (
+	// Returned when the merge source and target are the same term
+	ErrTermMergeSameTerm = errors.New("can not merge one term with itself")
+	// Returned when the merge source and target are from different vocabularies
+	ErrTermMergeVocabulary = errors.New("can not merge terms from different vocabularies")
+)
+
+// TermRedirectModel - Stores old term ids that now should redirect to other term, created on term merge
+type TermRedirectModel struct {
+	ID             uint64    `gorm:"primaryKey;column:id" json:"id"`
+	SourceID       uint64    `gorm:"uniqueIndex:term_redirects_sourceId_IDX;column:sourceId;type:int(11);not null" json:"sourceId"`
+	TargetID       uint64    `gorm:"index:term_redirects_targetId_IDX;column:targetId;type:int(11);not null" json:"targetId"`
+	VocabularyName string    `gorm:"column:vocabularyName;type:varchar(255);not null;default:Tags" json:"vocabularyName"`
+	CreatedAt      time.Time `gorm:"column:createdAt;type:datetime;not null" json:"createdAt"`
+	UpdatedAt      time.Time `gorm:"column:updatedAt;type:datetime;not null" json:"updatedAt"`
+}
+
+// TableName - Set db table name for term redirect model
+func (r *TermRedirectModel) TableName() string {
+	return "term_redirects"
+}
+
+// Find the redirect for one old term id, target.ID will be 0 if not found
+func TermRedirectFindOne(sourceId string, record *TermRedirectModel) error {
+	return TermRedirectFindOneContext(context.Background(), sourceId, record)
+}
+
+// TermRedirectFindOneContext - TermRedirectFindOne using ctx in the database queries
+func TermRedirectFindOneContext(ctx context.Context, sourceId string, record *TermRedirectModel) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	return db.Where("sourceId = ?", sourceId).
+		Limit(1).
+		Find(record).Error
+}
+
+// Merge the source term in the target term.
+// All source associations are moved to the target, associations that would be duplicated in the same
+// record field are removed, the source children and aliases are moved to the target, the source text
+// becomes one target alias and the source term is deleted, leaving one redirect from the source id to the target
+func TermMerge(source, target *TermModel) error {
+	return TermMergeContext(context.Background(), source, target)
+}
+
+// TermMergeContext - TermMerge using ctx in the database queries
+func TermMergeContext(ctx context.Context, source, target *TermModel) error {
+	if source.ID == target.ID {
+		return ErrTermMergeSameTerm
+	}
+
+	if source.VocabularyName != target.VocabularyName {
+		return ErrTermMergeVocabulary
+	}
+
+	// the target is lifted to the source parent if it is in the source subtree, moving the source children
+	// below one descendant of the target would create one cycle
+	ancestors := []TermModel{}
+	err := TermFindAncestorsContext(ctx, target, &ancestors)
+	if err != nil {
+		return errors.Wrap(err, "TermMerge error on find target ancestors")
+	}
+
+	isSourceDescendant := false
+	for i := range ancestors {
+		if ancestors[i].ID == source.ID {
+			isSourceDescendant = true
+			break
+		}
+	}
+
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		sourceAssocs := []ModelstermsModel{}
+		err := tx.Where("termId = ?", source.ID).Find(&sourceAssocs).Error
+		if err != nil {
+			return errors.Wrap(err, "TermMerge error on find source assocs")
+		}
+
+		targetAssocs := []ModelstermsModel{}
+		err = tx.Where("termId = ?", target.ID).Find(&targetAssocs).Error
+		if err != nil {
+			return errors.Wrap(err, "TermMerge error on find target assocs")
+		}
+
+		targetKeys := map[string]bool{}
+		for i := range targetAssocs {
+			targetKeys[modelstermsRecordFieldKey(&targetAssocs[i])] = true
+		}
+
+		duplicatedIds := []uint64{}
+		movedIds := []uint64{}
+		for i := range sourceAssocs {
+			key := modelstermsRecordFieldKey(&sourceAssocs[i])
+			if targetKeys[key] {
+				duplicatedIds = append(duplicatedIds, sourceAssocs[i].ID)
+				continue
+			}
+
+			targetKeys[key] = true
+			movedIds = append(movedIds, sourceAssocs[i].ID)
+		}
+
+		if len(duplicatedIds) > 0 {
+			err = tx.Where("id IN ?", duplicatedIds).Delete(&ModelstermsModel{}).Error
+			if err != nil {
+				return errors.Wrap(err, "TermMerge error on delete duplicated assocs")
+			}
+		}
+
+		if len(movedIds) > 0 {
+			err = tx.Model(&ModelstermsModel{}).
+				Where("id IN ?", movedIds).
+				Updates(map[string]interface{}{
+					"termId":         target.ID,
+					"vocabularyName": target.VocabularyName,
+				}).Error
+			if err != nil {
+				return errors.Wrap(err, "TermMerge error on move assocs")
+			}
+		}
+
+		if isSourceDescendant {
+			target.ParentID = source.ParentID
+			err = tx.Model(target).Update("parentId", target.ParentID).Error
+			if err != nil {
+				return errors.Wrap(err, "TermMerge error on update target parent")
+			}
+		}
+
+		// without hooks, the children search index does not change
+		err = tx.Model(&TermModel{}).
+			Where("parentId = ? AND id != ?", source.ID, target.ID).
+			UpdateColumn("parentId", target.ID).Error
+		if err != nil {
+			return errors.Wrap(err, "TermMerge error on move children")
+		}
+
+		// old redirects to the source now point to the target
+		err = tx.Model(&TermRedirectModel{}).
+			Where("targetId = ?", source.ID).
+			Update("targetId", target.ID).Error
+		if err != nil {
+			return errors.Wrap(err, "TermMerge error on update redirects")
+		}
+
+		// source aliases and the source text are now target aliases
+		err = tx.Model(&TermAliasModel{}).
+			Where("termId = ?", source.ID).
+			Update("termId", target.ID).Error
+		if err != nil {
+			return errors.Wrap(err, "TermMerge error on move aliases")
+		}
+
+		err = tx.Where("termId = ? AND text = ?", target.ID, target.Text).
+			Delete(&TermAliasModel{}).Error
+		if err != nil {
+			return errors.Wrap(err, "TermMerge error on delete target text alias")
+		}
+
+		if source.Text != target.Text {
+			alias := TermAliasModel{
+				TermID:         target.ID,
+				Text:           source.Text,
+				VocabularyName: target.VocabularyName,
+			}
+			err = tx.Create(&alias).Error
+			if err != nil {
+				return errors.Wrap(err, "TermMerge error on create source text alias")
+			}
+		}
+
+		// source old urls by slug now point to the target
+		err = tx.Model(&TermSlugRedirectModel{}).
+			Where("termId = ?", source.ID).
+			Update("termId", target.ID).Error
+		if err != nil {
+			return errors.Wrap(err, "TermMerge error on move slug redirects")
+		}
+
+		if source.Slug != "" {
+			slugRedirect := TermSlugRedirectModel{
+				TermID:         target.ID,
+				Slug:           source.Slug,
+				VocabularyName: source.VocabularyName,
+			}
+			err = tx.Create(&slugRedirect).Error
+			if err != nil {
+				return errors.Wrap(err, "TermMerge error on create slug redirect")
+			}
+		}
+
+		// source translations are moved to the target if the target has no translation in the locale
+		err = tx.Model(&TermTranslationModel{}).
+			Where("termId = ? AND locale NOT IN (?)", source.ID,
+				tx.Model(&TermTranslationModel{}).Select("locale").Where("termId = ?", target.ID)).
+			Update("termId", target.ID).Error
+		if err != nil {
+			return errors.Wrap(err, "TermMerge error on move translations")
+		}
+
+		err = tx.Where("termId = ?", source.ID).Delete(&TermTranslationModel{}).Error
+		if err != nil {
+			return errors.Wrap(err, "TermMerge error on delete source translations")
+		}
+
+		redirect := TermRedirectModel{
+			SourceID:       source.ID,
+			TargetID:       target.ID,
+			VocabularyName: source.VocabularyName,
+		}
+		err = tx.Create(&redirect).Error
+		if err != nil {
+			return errors.Wrap(err, "TermMerge error on create redirect")
+		}
+
+		err = tx.Unscoped().Delete(source).Error
+		if err != nil {
+			return errors.Wrap(err, "TermMerge error on delete source")
+		}
+
+		err = termUpdateUsage(tx, []uint64{target.ID})
+		if err != nil {
+			return errors.Wrap(err, "TermMerge error on update target usage")
+		}
+
+		return nil
+	})
+}
+
+// Merge terms by id, see TermMerge
+func TermMergeByID(sourceId, targetId string, target *TermModel) error {
+	return TermMergeByIDContext(context.Background(), sourceId, targetId, target)
+}
+
+// TermMergeByIDContext - TermMergeByID using ctx in the database queries
+func TermMergeByIDContext(ctx context.Context, sourceId, targetId string, target *TermModel) error {
+	source := TermModel{}
+	err := TermFindOneContext(ctx, sourceId, &source)
+	if err != nil {
+		return errors.Wrap(err, "TermMergeByID error on find source")
+	}
+
+	err = TermFindOneContext(ctx, targetId, target)
+	if err != nil {
+		return errors.Wrap(err, "TermMergeByID error on find target")
+	}
+
+	return TermMergeContext(ctx, &source, target)
+}
+
+func modelstermsRecordFieldKey(r *ModelstermsModel) string {
+	return r.ModelName + ":" + strconv.FormatUint(r.ModelID, 10) + ":" + r.Field
+}
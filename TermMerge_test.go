@@ -0,0 +1,110 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTermMerge(t *testing.T) {
+	assert := assert.New(t)
+	db := GetAppInstance().GetDB()
+
+	source := TermModel{Text: "golang", VocabularyName: "mergetags", Translations: []TermTranslationModel{
+		{Locale: "pt-BR", Text: "linguagem go"},
+		{Locale: "es", Text: "lenguaje go"},
+	}}
+	assert.Nil(source.Save())
+	target := TermModel{Text: "go", VocabularyName: "mergetags", Translations: []TermTranslationModel{
+		{Locale: "pt-BR", Text: "go"},
+	}}
+	assert.Nil(target.Save())
+
+	// record 1 has both terms, record 2 only the source
+	for _, a := range []struct {
+		modelID uint64
+		termID  uint64
+	}{{1, source.ID}, {1, target.ID}, {2, source.ID}} {
+		assoc, _ := NewModelsterms("mergetags", "mergepost", "tags", a.modelID, a.termID)
+		assert.Nil(db.Create(&assoc).Error)
+	}
+
+	assert.Nil(TermMerge(&source, &target))
+
+	var count int64
+	assert.Nil(db.Model(&ModelstermsModel{}).Where("termId = ?", target.ID).Count(&count).Error)
+	assert.Equal(int64(2), count)
+	assert.Nil(db.Model(&ModelstermsModel{}).Where("termId = ?", source.ID).Count(&count).Error)
+	assert.Equal(int64(0), count)
+
+	deleted := TermModel{}
+	assert.Nil(db.Where("id = ?", source.ID).Limit(1).Find(&deleted).Error)
+	assert.Equal(uint64(0), deleted.ID)
+
+	alias := TermAliasModel{}
+	assert.Nil(TermAliasFindOneByText("golang", "mergetags", &alias))
+	assert.Equal(target.ID, alias.TermID)
+
+	redirect := TermRedirectModel{}
+	assert.Nil(TermRedirectFindOne(source.GetIDString(), &redirect))
+	assert.Equal(target.ID, redirect.TargetID)
+
+	// the target keeps its translations and gets the source ones in other locales
+	merged := TermModel{}
+	assert.Nil(TermFindOne(target.GetIDString(), &merged))
+	assert.Nil(merged.LoadTranslations())
+	assert.Equal("go", merged.GetLocalizedText("pt-BR"))
+	assert.Equal("lenguaje go", merged.GetLocalizedText("es"))
+	assert.Nil(db.Model(&TermTranslationModel{}).Where("termId = ?", source.ID).Count(&count).Error)
+	assert.Equal(int64(0), count)
+
+	assert.ErrorIs(TermMerge(&target, &target), ErrTermMergeSameTerm)
+	other := TermModel{Text: "go", VocabularyName: "mergeother"}
+	assert.Nil(other.Save())
+	assert.ErrorIs(TermMerge(&other, &target), ErrTermMergeVocabulary)
+}
+
+func TestTermMerge_IntoDescendant(t *testing.T) {
+	assert := assert.New(t)
+	GetAppInstance()
+
+	t.Run("Should lift one child target to the source parent", func(t *testing.T) {
+		root, child, grandchild, _ := saveTermTreeStub(assert, "mergechild")
+
+		assert.Nil(TermMerge(&root, &child))
+
+		merged := TermModel{}
+		assert.Nil(TermFindOne(child.GetIDString(), &merged))
+		assert.Nil(merged.ParentID)
+
+		moved := TermModel{}
+		assert.Nil(TermFindOne(grandchild.GetIDString(), &moved))
+		assert.Equal(child.ID, *moved.ParentID)
+	})
+
+	t.Run("Should lift one grandchild target without creating cycles", func(t *testing.T) {
+		root, child, grandchild, other := saveTermTreeStub(assert, "mergegrandchild")
+		top := TermModel{Text: "top", VocabularyName: "mergegrandchild"}
+		assert.Nil(top.Save())
+		root.ParentID = &top.ID
+		assert.Nil(root.Save())
+
+		assert.Nil(TermMerge(&root, &grandchild))
+
+		merged := TermModel{}
+		assert.Nil(TermFindOne(grandchild.GetIDString(), &merged))
+		assert.Equal(top.ID, *merged.ParentID)
+
+		moved := TermModel{}
+		assert.Nil(TermFindOne(child.GetIDString(), &moved))
+		assert.Equal(grandchild.ID, *moved.ParentID)
+
+		tree, err := TermFindTree("mergegrandchild")
+		assert.Nil(err)
+		assert.Equal(2, len(tree))
+		assert.Equal(other.ID, tree[0].ID)
+		assert.Equal(top.ID, tree[1].ID)
+		assert.Equal(grandchild.ID, tree[1].Children[0].ID)
+		assert.Equal(child.ID, tree[1].Children[0].Children[0].ID)
+	})
+}
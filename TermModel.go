@@ -1,6 +1,7 @@
 package tags
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -16,14 +17,29 @@ import (
 )
 
 type TermModel struct {
-	ID             uint64    `gorm:"primaryKey;column:id" json:"id" filter:"param:id;type:number"`
-	Text           string    `gorm:"column:text;type:varchar(255);not null" json:"text" filter:"param:text;type:string"`
-	Description    string    `gorm:"column:description;type:text" json:"description" filter:"param:description;type:string"`
-	VocabularyName string    `gorm:"column:vocabularyName;type:varchar(255);not null;default:Tags" json:"vocabularyName" filter:"param:vocabularyName;type:string"`
+	ID             uint64  `gorm:"primaryKey;column:id" json:"id" filter:"param:id;type:number"`
+	Text           string  `gorm:"column:text;type:varchar(255);not null" json:"text" filter:"param:text;type:string"`
+	Description    string  `gorm:"column:description;type:text" json:"description" filter:"param:description;type:string"`
+	VocabularyName string  `gorm:"uniqueIndex:terms_vocabularyName_slug_IDX;column:vocabularyName;type:varchar(255);not null;default:Tags" json:"vocabularyName" filter:"param:vocabularyName;type:string"`
+	Slug           string  `gorm:"uniqueIndex:terms_vocabularyName_slug_IDX;column:slug;type:varchar(255)" json:"slug" filter:"param:slug;type:string"`
+	ParentID       *uint64 `gorm:"index:terms_parentId_IDX;column:parentId;type:int(11)" json:"parentId" filter:"param:parentId;type:number"`
+	// Lowercase text without accents used in searches, see TermSearchText
+	SearchText string `gorm:"index:terms_searchText_IDX;column:searchText;type:varchar(255)" json:"-"`
+	// Number of associations with this term, kept by the field configurations and modelsterms methods
+	Usage int64 `gorm:"index:terms_usageCount_IDX;column:usageCount;not null;default:0" json:"usage"`
+	// Exclude the term page from sitemaps and search engines, see TermSEO
+	NoIndex bool `gorm:"column:noIndex;not null;default:false" json:"noIndex"`
+	// Term page title and description overrides for search engines, the localized text and description are used if empty
+	SEOTitle       string    `gorm:"column:seoTitle;type:varchar(255)" json:"seoTitle"`
+	SEODescription string    `gorm:"column:seoDescription;type:text" json:"seoDescription"`
 	CreatedAt      time.Time `gorm:"column:createdAt;type:datetime;not null" json:"createdAt"`
 	UpdatedAt      time.Time `gorm:"column:updatedAt;type:datetime;not null" json:"updatedAt"`
 
-	LinkPermanent string `gorm:"-" json:"linkPermanent"`
+	LinkPermanent string                 `gorm:"-" json:"linkPermanent"`
+	Aliases       []string               `gorm:"-" json:"aliases"`
+	Translations  []TermTranslationModel `gorm:"-" json:"translations"`
+	// Locale used to build the LinkPermanent, empty for the default locale
+	Locale string `gorm:"-" json:"locale,omitempty"`
 }
 
 // TableName - Set db table name for term model
@@ -42,23 +58,67 @@ func (r *TermModel) ToJSON() string {
 
 // Save - Create if is new or update
 func (m *TermModel) Save() error {
-	var err error
-	db := catu.GetDefaultDatabaseConnection()
+	return m.SaveContext(context.Background())
+}
+
+// SaveContext - Save using ctx in the database queries. The slug, term, aliases and translations are saved in one transaction
+func (m *TermModel) SaveContext(ctx context.Context) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	isNew := m.ID == 0
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return m.save(tx)
+	})
+	if err != nil && isNew {
+		m.ID = 0
+	}
+
+	return err
+}
 
+// Save the term with its slug, aliases and translations in the tx transaction
+func (m *TermModel) save(tx *gorm.DB) error {
+	// the term text can not be used as alias by other term
+	var count int64
+	err := tx.Model(&TermAliasModel{}).
+		Where("vocabularyName = ? AND text = ? AND termId != ?", m.VocabularyName, m.Text, m.ID).
+		Count(&count).Error
+	if err != nil {
+		return errors.Wrap(err, "TermModel.Save error on check aliases")
+	}
+
+	if count > 0 {
+		return ErrTermAliasConflict
+	}
+
+	err = m.setSlug(tx)
+	if err != nil {
+		return err
+	}
+
+	// the usage is only changed with the associations
 	if m.ID == 0 {
-		// create ....
-		r := db.Create(m)
-		if r.Error != nil {
-			return r.Error
-		}
+		err = tx.Omit("usageCount").Create(m).Error
 	} else {
-		// update ...
-		err = db.Save(m).Error
+		err = tx.Omit("usageCount").Save(m).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	// nil aliases and translations are not loaded or set, so keep the saved ones
+	if m.Aliases != nil {
+		err = m.saveAliases(tx)
 		if err != nil {
 			return err
 		}
 	}
 
+	if m.Translations != nil {
+		return m.saveTranslations(tx)
+	}
+
 	return nil
 }
 
@@ -68,7 +128,19 @@ func (r *TermModel) LoadTeaserData() error {
 }
 
 func (r *TermModel) LoadData() error {
-	return r.LoadTeaserData()
+	return r.LoadDataContext(context.Background())
+}
+
+// LoadDataContext - LoadData using ctx in the database queries
+func (r *TermModel) LoadDataContext(ctx context.Context) error {
+	r.LoadTeaserData()
+
+	err := r.LoadAliasesContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.LoadTranslationsContext(ctx)
 }
 
 func (r *TermModel) GetPath() string {
@@ -82,7 +154,9 @@ func (r *TermModel) GetPath() string {
 		subPath += r.VocabularyName + "/"
 	}
 
-	if r.ID != 0 {
+	if r.Slug != "" {
+		path += subPath + "term/" + r.Slug
+	} else if r.ID != 0 {
 		path += subPath + "term/" + r.GetIDString()
 	}
 
@@ -91,13 +165,51 @@ func (r *TermModel) GetPath() string {
 
 func (r *TermModel) LoadPath() error {
 	app := catu.GetApp()
-	r.LinkPermanent = app.GetConfiguration().Get("APP_ORIGIN") + r.GetPath()
+	r.LinkPermanent = app.GetConfiguration().Get("APP_ORIGIN") + r.GetLocalizedPath(r.Locale)
 	return nil
 }
 
+// Delete - Delete the term, its redirects, aliases, old slugs and translations and move its children to the deleted term parent
 func (r *TermModel) Delete() error {
-	db := catu.GetDefaultDatabaseConnection()
-	return db.Unscoped().Delete(r).Error
+	return r.DeleteContext(context.Background())
+}
+
+// DeleteContext - Delete using ctx in the database queries. The children are moved to the term parent and the
+// term data is deleted in one transaction
+func (r *TermModel) DeleteContext(ctx context.Context) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// without hooks, the children search index does not change
+		err := tx.Model(&TermModel{}).
+			Where("parentId = ?", r.ID).
+			UpdateColumn("parentId", r.ParentID).Error
+		if err != nil {
+			return errors.Wrap(err, "TermModel.Delete error on move children")
+		}
+
+		err = tx.Where("targetId = ?", r.ID).Delete(&TermRedirectModel{}).Error
+		if err != nil {
+			return errors.Wrap(err, "TermModel.Delete error on delete redirects")
+		}
+
+		err = tx.Where("termId = ?", r.ID).Delete(&TermAliasModel{}).Error
+		if err != nil {
+			return errors.Wrap(err, "TermModel.Delete error on delete aliases")
+		}
+
+		err = tx.Where("termId = ?", r.ID).Delete(&TermSlugRedirectModel{}).Error
+		if err != nil {
+			return errors.Wrap(err, "TermModel.Delete error on delete slug redirects")
+		}
+
+		err = tx.Where("termId = ?", r.ID).Delete(&TermTranslationModel{}).Error
+		if err != nil {
+			return errors.Wrap(err, "TermModel.Delete error on delete translations")
+		}
+
+		return tx.Unscoped().Delete(r).Error
+	})
 }
 
 func NewTerm() (TermModel, error) {
@@ -107,7 +219,12 @@ func NewTerm() (TermModel, error) {
 
 // Find One term by ID
 func TermFindOne(id string, record *TermModel) error {
-	db := catu.GetDefaultDatabaseConnection()
+	return TermFindOneContext(context.Background(), id, record)
+}
+
+// TermFindOneContext - TermFindOne using ctx in the database queries
+func TermFindOneContext(ctx context.Context, id string, record *TermModel) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
 
 	err := db.First(&record, id).Error
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -117,23 +234,63 @@ func TermFindOne(id string, record *TermModel) error {
 	return err
 }
 
-// Find One term by vocabulary / term
+// Find One term by vocabulary / term, aliases are resolved to its term
 func TermFindOneByText(text, vocabularyName string, record *TermModel) error {
-	db := catu.GetDefaultDatabaseConnection()
+	return TermFindOneByTextContext(context.Background(), text, vocabularyName, record)
+}
 
+// TermFindOneByTextContext - TermFindOneByText using ctx in the database queries
+func TermFindOneByTextContext(ctx context.Context, text, vocabularyName string, record *TermModel) error {
+	return termFindOneByText(catu.GetDefaultDatabaseConnection().WithContext(ctx), text, vocabularyName, record)
+}
+
+func termFindOneByText(db *gorm.DB, text, vocabularyName string, record *TermModel) error {
 	err := db.Where("text = ? AND vocabularyName = ?", text, vocabularyName).
 		First(record).Error
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return err
 	}
 
+	if record.ID != 0 {
+		return nil
+	}
+
+	alias := TermAliasModel{}
+	err = termAliasFindOneByText(db, text, vocabularyName, &alias)
+	if err != nil {
+		return err
+	}
+
+	if alias.ID == 0 {
+		return nil
+	}
+
+	err = db.Where("id = ?", alias.TermID).
+		First(record).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
 	return nil
 }
 
+// Find many terms by vocabulary / texts, aliases are resolved to its terms
 func TermFindManyByText(texts []string, vocabularyName string, records *[]TermModel) error {
-	db := catu.GetDefaultDatabaseConnection()
+	return TermFindManyByTextContext(context.Background(), texts, vocabularyName, records)
+}
+
+// TermFindManyByTextContext - TermFindManyByText using ctx in the database queries
+func TermFindManyByTextContext(ctx context.Context, texts []string, vocabularyName string, records *[]TermModel) error {
+	return termFindManyByText(catu.GetDefaultDatabaseConnection().WithContext(ctx), texts, vocabularyName, records)
+}
+
+func termFindManyByText(db *gorm.DB, texts []string, vocabularyName string, records *[]TermModel) error {
+	texts, err := termResolveAliasTexts(db, texts, vocabularyName)
+	if err != nil {
+		return err
+	}
 
-	err := db.Where("text IN ? AND vocabularyName = ?", texts, vocabularyName).
+	err = db.Where("text IN ? AND vocabularyName = ?", texts, vocabularyName).
 		Find(records).Error
 	if err != nil {
 		return err
@@ -147,7 +304,7 @@ func FindTermTextsAndCount(ctx *catu.RequestContext) ([]string, int64, error) {
 
 	var count int64
 	var records []TermModel
-	err := TermQueryAndCountReq(&TermQueryOpts{
+	err := TermQueryAndCountReqContext(ctx.Request().Context(), &TermQueryOpts{
 		Records: &records,
 		Count:   &count,
 		Limit:   ctx.GetLimit(),
@@ -178,26 +335,20 @@ type TermQueryOpts struct {
 }
 
 func TermQueryAndCountReq(opts *TermQueryOpts) error {
-	db := catu.GetDefaultDatabaseConnection()
-
-	c := opts.C
-
-	q := c.QueryParam("q")
-
-	text := c.QueryParam("text")
-	term := c.QueryParam("term")
+	return TermQueryAndCountReqContext(context.Background(), opts)
+}
 
-	if text == "" && term != "" {
-		text = term
-	}
+// TermQueryAndCountReqContext - TermQueryAndCountReq using ctx in the database queries
+func TermQueryAndCountReqContext(ctx context.Context, opts *TermQueryOpts) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
 
-	vocabularyName := c.Param("vocabulary")
+	c := opts.C
 
 	query := db
 
-	ctx := c.(*catu.RequestContext)
+	rctx := c.(*catu.RequestContext)
 
-	queryI, err := ctx.Query.SetDatabaseQueryForModel(query, &TermModel{})
+	queryI, err := rctx.Query.SetDatabaseQueryForModel(query, &TermModel{})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": fmt.Sprintf("%+v\n", err),
@@ -205,28 +356,24 @@ func TermQueryAndCountReq(opts *TermQueryOpts) error {
 	}
 	query = queryI.(*gorm.DB)
 
-	if q != "" {
-		query = query.Where(
-			db.Where("text LIKE ?", "%"+q+"%").
-				Or(db.Where("description LIKE ?", "%"+q+"%")),
-		)
-	}
+	query = termQueryReqFilters(db, query, c)
 
-	if vocabularyName != "" {
-		query = query.Where("vocabularyName = ?", vocabularyName)
-	}
+	orderColumn, orderIsDesc, orderValid := helpers.ParseUrlQueryOrder(c.QueryParam("order"), c.QueryParam("sort"), c.QueryParam("sortDirection"))
 
-	if text != "" {
-		query = query.Where("text LIKE ?", text+"%")
+	// usage is a reserved word in some databases
+	if orderColumn == "usage" {
+		orderColumn = "usageCount"
 	}
 
-	orderColumn, orderIsDesc, orderValid := helpers.ParseUrlQueryOrder(c.QueryParam("order"), c.QueryParam("sort"), c.QueryParam("sortDirection"))
-
 	if orderValid {
 		query = query.Order(clause.OrderByColumn{
 			Column: clause.Column{Table: clause.CurrentTable, Name: orderColumn},
 			Desc:   orderIsDesc,
 		})
+
+		if orderColumn == "usageCount" {
+			query = query.Order("text ASC")
+		}
 	} else {
 		query = query.
 			Order("createdAt DESC").
@@ -241,29 +388,25 @@ func TermQueryAndCountReq(opts *TermQueryOpts) error {
 		return r.Error
 	}
 
-	return TermCountReq(opts)
+	return TermCountReqContext(ctx, opts)
 }
 
 func TermCountReq(opts *TermQueryOpts) error {
-	db := catu.GetDefaultDatabaseConnection()
+	return TermCountReqContext(context.Background(), opts)
+}
 
-	c := opts.C
+// TermCountReqContext - TermCountReq using ctx in the database queries
+func TermCountReqContext(ctx context.Context, opts *TermQueryOpts) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
 
-	q := c.QueryParam("q")
+	c := opts.C
 
-	ctx := c.(*catu.RequestContext)
+	rctx := c.(*catu.RequestContext)
 
 	// Count ...
 	queryCount := db
 
-	if q != "" {
-		queryCount = queryCount.Or(
-			db.Where("text LIKE ?", "%"+q+"%"),
-			db.Where("description LIKE ?", "%"+q+"%"),
-		)
-	}
-
-	queryICount, err := ctx.Query.SetDatabaseQueryForModel(queryCount, &VocabularyModel{})
+	queryICount, err := rctx.Query.SetDatabaseQueryForModel(queryCount, &TermModel{})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": fmt.Sprintf("%+v\n", err),
@@ -271,7 +414,56 @@ func TermCountReq(opts *TermQueryOpts) error {
 	}
 	queryCount = queryICount.(*gorm.DB)
 
+	queryCount = termQueryReqFilters(db, queryCount, c)
+
 	return queryCount.
 		Table("terms").
 		Count(opts.Count).Error
 }
+
+// Apply the request q, text / term and vocabulary filters, shared by the term query and count.
+// Texts are also searched in the request locale translations
+func termQueryReqFilters(db, query *gorm.DB, c echo.Context) *gorm.DB {
+	q := c.QueryParam("q")
+
+	text := c.QueryParam("text")
+	term := c.QueryParam("term")
+
+	if text == "" && term != "" {
+		text = term
+	}
+
+	vocabularyName := c.Param("vocabulary")
+	locale := GetRequestLocale(c)
+
+	if q != "" {
+		search := GetTermSearchBackend().Where(db, q)
+
+		// also search in the request locale translations
+		if translated := termTranslationsTermIDs(db, locale); translated != nil {
+			search = search.Or("id IN (?)", translated.Where(
+				db.Where("text LIKE ?", "%"+q+"%").
+					Or(db.Where("description LIKE ?", "%"+q+"%")),
+			))
+		}
+
+		query = query.Where(search)
+	}
+
+	if vocabularyName != "" {
+		query = query.Where("vocabularyName = ?", vocabularyName)
+	}
+
+	if text != "" {
+		if translated := termTranslationsTermIDs(db, locale); translated != nil {
+			query = query.Where(
+				db.Where("text LIKE ?", text+"%").
+					Or("id IN (?)", translated.Where("text LIKE ?", text+"%")),
+			)
+		} else {
+			query = query.Where("text LIKE ?", text+"%")
+		}
+	}
+
+	return query
+}
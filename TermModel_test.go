@@ -1,186 +1,298 @@
 package tags
 
-// func TestUpdateFieldTermById_Category(t *testing.T) {
-// 	assert := assert.New(t)
+import (
+	"errors"
+	"log"
+	"testing"
 
-// 	app := GetAppInstance()
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
 
-// 	var categoryFieldCfg = NewCategoryFieldConfiguration("category", "content", "cat")
+func TestFieldConfigurationAdd_Category(t *testing.T) {
+	assert := assert.New(t)
 
-// 	// var tagFieldCfg = NewTagFieldConfiguration("tags", "content", "tagis")
+	app := GetAppInstance()
 
-// 	t.Run("Should Return error if try to associate with a not existent category", func(t *testing.T) {
-// 		modelId, termText := "10", "somethingUnknow"
+	var categoryFieldCfg = NewCategoryFieldConfiguration("category", "content", "cat")
 
-// 		term, assoc, err := UpdateFieldTermById(modelId, termText, &categoryFieldCfg)
-// 		assert.NotNil(err)
-// 		assert.Equal("UpdateFieldTermById term dont exists and cant create for this field", err.Error())
+	t.Run("Should Return error if try to associate with a not existent category", func(t *testing.T) {
+		modelId, termText := "10", "somethingUnknow"
 
-// 		assert.Nil(term)
-// 		assert.Nil(assoc)
-// 	})
+		term, assoc, err := categoryFieldCfg.Add(modelId, termText)
+		assert.NotNil(err)
+		assert.ErrorIs(err, ErrFieldCanNotCreateTerm)
 
-// 	t.Run("Should associate model field with a valid category", func(t *testing.T) {
-// 		modelId, termText := "2", "Gaming"
+		assert.Nil(term)
+		assert.Nil(assoc)
+	})
 
-// 		savedCat := TermModel{
-// 			Text:           termText,
-// 			VocabularyName: categoryFieldCfg.VocabularyName,
-// 		}
+	t.Run("Should associate model field with a valid category", func(t *testing.T) {
+		modelId, termText := "2", "Gaming"
 
-// 		err := savedCat.Save()
-// 		assert.Nil(err)
+		savedCat := TermModel{
+			Text:           termText,
+			VocabularyName: categoryFieldCfg.GetVocabularyName(),
+		}
 
-// 		term, assoc, err := UpdateFieldTermById(modelId, termText, &categoryFieldCfg)
-// 		assert.Nil(err)
+		err := savedCat.Save()
+		assert.Nil(err)
 
-// 		assert.NotNil(term)
-// 		assert.NotNil(assoc)
+		term, assoc, err := categoryFieldCfg.Add(modelId, termText)
+		assert.Nil(err)
 
-// 		assert.Equal(savedCat.ID, term.ID)
-// 		assert.Equal(savedCat.Text, term.Text)
-// 		assert.Equal(savedCat.ID, *assoc.TermID)
-// 	})
-
-// 	t.Run("Should remove old association and associate the new one", func(t *testing.T) {
-// 		modelId, termText, oldTermText := "3", "Health", "Tech"
-
-// 		savedCat := TermModel{
-// 			Text:           termText,
-// 			VocabularyName: categoryFieldCfg.VocabularyName,
-// 		}
-
-// 		err := savedCat.Save()
-// 		assert.Nil(err)
-
-// 		savedCat2 := TermModel{
-// 			Text:           oldTermText,
-// 			VocabularyName: categoryFieldCfg.VocabularyName,
-// 		}
-
-// 		err = savedCat2.Save()
-// 		assert.Nil(err)
-
-// 		assocSaved := ModelstermsModel{
-// 			ModelName:      categoryFieldCfg.ModelName,
-// 			ModelID:        3,
-// 			Field:          categoryFieldCfg.FieldName,
-// 			VocabularyName: categoryFieldCfg.VocabularyName,
-// 			TermID:         &savedCat2.ID,
-// 		}
-
-// 		err = assocSaved.Save()
-// 		assert.Nil(err)
-
-// 		var oldSavedTerm TermModel
-// 		err = TermFindOneInRecord(categoryFieldCfg.ModelName, categoryFieldCfg.FieldName, modelId, &oldSavedTerm)
-// 		assert.Nil(err)
-
-// 		assert.Equal(oldTermText, oldSavedTerm.Text)
-// 		assert.Equal(savedCat2.ID, oldSavedTerm.ID)
-
-// 		term, assoc, err := UpdateFieldTermById(modelId, termText, &categoryFieldCfg)
-// 		assert.Nil(err)
-
-// 		assert.NotNil(term)
-// 		assert.NotNil(assoc)
-
-// 		assert.Equal(savedCat.ID, term.ID)
-// 		assert.Equal(savedCat.Text, term.Text)
-// 		assert.Equal(savedCat.ID, *assoc.TermID)
-// 	})
-
-// 	t.Cleanup(func() {
-// 		db := app.DB
-// 		r := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&ModelstermsModel{})
-// 		if r.Error != nil {
-// 			log.Println("Error on delete db modelsTerms", r.Error, r.RowsAffected)
-// 		}
-// 	})
-// }
-
-// func TestUpdateFieldTermById_Term(t *testing.T) {
-// 	assert := assert.New(t)
-// 	app := GetAppInstance()
-// 	var cfg = NewTagFieldConfiguration("tags", "content", "tagis")
-
-// 	t.Run("Should Return create a new term and associate", func(t *testing.T) {
-// 		modelId := "11"
-// 		terms := []string{"somethingNew1", "meToo2"}
-
-// 		err := UpdateFieldTermsById(modelId, terms, &cfg)
-// 		assert.Nil(err)
-
-// 		afterSaveTerms := []TermModel{}
-// 		err = TermFindManyInRecord(cfg.VocabularyName, cfg.ModelName, cfg.FieldName, modelId, &afterSaveTerms)
-// 		assert.Nil(err)
-// 		assert.Equal(2, len(afterSaveTerms))
-// 		assert.Equal(terms[0], afterSaveTerms[0].Text)
-// 		assert.Equal(terms[1], afterSaveTerms[1].Text)
-// 	})
-
-// 	t.Run("Should remove 1 terms and add 2 new ones", func(t *testing.T) {
-// 		modelId := "12"
-// 		oldTerms := []string{"oldOne1"}
-// 		terms := []string{"somethingNew2", "meToo3"}
-
-// 		err := UpdateFieldTermsById(modelId, oldTerms, &cfg)
-// 		assert.Nil(err)
-
-// 		afterSave1Terms := []TermModel{}
-// 		err = TermFindManyInRecord(cfg.VocabularyName, cfg.ModelName, cfg.FieldName, modelId, &afterSave1Terms)
-// 		assert.Nil(err)
-// 		assert.Equal(1, len(afterSave1Terms))
-// 		assert.Equal(oldTerms[0], afterSave1Terms[0].Text)
-
-// 		err = UpdateFieldTermsById(modelId, terms, &cfg)
-// 		assert.Nil(err)
-
-// 		afterSave2Terms := []TermModel{}
-// 		err = TermFindManyInRecord(cfg.VocabularyName, cfg.ModelName, cfg.FieldName, modelId, &afterSave2Terms)
-// 		assert.Nil(err)
-// 		assert.Equal(2, len(afterSave2Terms))
-// 		assert.Equal(terms[0], afterSave2Terms[0].Text)
-// 		assert.Equal(terms[1], afterSave2Terms[1].Text)
-// 	})
-
-// 	t.Run("Should remove 2, keep 2 terms and add 2 new ones", func(t *testing.T) {
-// 		modelId := "13"
-// 		oldTerms := []string{"oldOne0", "oldOne1", "oldOne2", "oldOne3"}
-// 		terms := []string{"oldOne2", "oldOne3", "somethingNew2", "meToo3"}
-
-// 		err := UpdateFieldTermsById(modelId, oldTerms, &cfg)
-// 		assert.Nil(err)
-
-// 		afterSave1Terms := []TermModel{}
-// 		err = TermFindManyInRecord(cfg.VocabularyName, cfg.ModelName, cfg.FieldName, modelId, &afterSave1Terms)
-// 		assert.Nil(err)
-
-// 		assert.Equal(4, len(afterSave1Terms))
-// 		assert.Equal(oldTerms[0], afterSave1Terms[0].Text)
-// 		assert.Equal(oldTerms[1], afterSave1Terms[1].Text)
-// 		assert.Equal(oldTerms[2], afterSave1Terms[2].Text)
-// 		assert.Equal(oldTerms[3], afterSave1Terms[3].Text)
-
-// 		err = UpdateFieldTermsById(modelId, terms, &cfg)
-// 		assert.Nil(err)
-
-// 		afterSave2Terms := []TermModel{}
-// 		err = TermFindManyInRecord(cfg.VocabularyName, cfg.ModelName, cfg.FieldName, modelId, &afterSave2Terms)
-// 		assert.Nil(err)
-
-// 		assert.Equal(4, len(afterSave2Terms))
-// 		assert.Equal(terms[0], afterSave2Terms[0].Text)
-// 		assert.Equal(terms[1], afterSave2Terms[1].Text)
-// 		assert.Equal(terms[2], afterSave2Terms[2].Text)
-// 		assert.Equal(terms[3], afterSave2Terms[3].Text)
-// 	})
-
-// 	t.Cleanup(func() {
-// 		db := app.DB
-// 		r := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&ModelstermsModel{})
-// 		if r.Error != nil {
-// 			log.Println("Error on delete db modelsTerms", r.Error, r.RowsAffected)
-// 		}
-// 	})
-// }
+		assert.NotNil(term)
+		assert.NotNil(assoc)
+
+		assert.Equal(savedCat.ID, term.ID)
+		assert.Equal(savedCat.Text, term.Text)
+		assert.Equal(savedCat.ID, *assoc.TermID)
+	})
+
+	t.Run("Should remove old association and associate the new one", func(t *testing.T) {
+		modelId, termText, oldTermText := "3", "Health", "Tech"
+
+		savedCat := TermModel{
+			Text:           termText,
+			VocabularyName: categoryFieldCfg.GetVocabularyName(),
+		}
+
+		err := savedCat.Save()
+		assert.Nil(err)
+
+		savedCat2 := TermModel{
+			Text:           oldTermText,
+			VocabularyName: categoryFieldCfg.GetVocabularyName(),
+		}
+
+		err = savedCat2.Save()
+		assert.Nil(err)
+
+		assocSaved := ModelstermsModel{
+			ModelName:      categoryFieldCfg.GetModelName(),
+			ModelID:        3,
+			Field:          categoryFieldCfg.GetFieldName(),
+			VocabularyName: categoryFieldCfg.GetVocabularyName(),
+			TermID:         &savedCat2.ID,
+		}
+
+		err = assocSaved.Save()
+		assert.Nil(err)
+
+		var oldSavedTerm TermModel
+		err = categoryFieldCfg.FindOneTerm(modelId, &oldSavedTerm)
+		assert.Nil(err)
+
+		assert.Equal(oldTermText, oldSavedTerm.Text)
+		assert.Equal(savedCat2.ID, oldSavedTerm.ID)
+
+		term, assoc, err := categoryFieldCfg.Add(modelId, termText)
+		assert.Nil(err)
+
+		assert.NotNil(term)
+		assert.NotNil(assoc)
+
+		assert.Equal(savedCat.ID, term.ID)
+		assert.Equal(savedCat.Text, term.Text)
+		assert.Equal(savedCat.ID, *assoc.TermID)
+
+		afterSaveTerms := []TermModel{}
+		err = categoryFieldCfg.FindManyTerm(modelId, &afterSaveTerms)
+		assert.Nil(err)
+		assert.Equal(1, len(afterSaveTerms))
+	})
+
+	t.Run("Should return error if try to set more than one category", func(t *testing.T) {
+		modelId := "4"
+
+		err := categoryFieldCfg.Update(modelId, []string{"Gaming", "Health"})
+		assert.ErrorIs(err, ErrFieldOnlyOneTerm)
+
+		err = categoryFieldCfg.AddMany(modelId, []string{"Gaming", "Health"})
+		assert.ErrorIs(err, ErrFieldOnlyOneTerm)
+
+		afterSaveTerms := []TermModel{}
+		err = categoryFieldCfg.FindManyTerm(modelId, &afterSaveTerms)
+		assert.Nil(err)
+		assert.Equal(0, len(afterSaveTerms))
+	})
+
+	t.Cleanup(func() {
+		db := app.GetDB()
+		r := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&ModelstermsModel{})
+		if r.Error != nil {
+			log.Println("Error on delete db modelsTerms", r.Error, r.RowsAffected)
+		}
+	})
+}
+
+func TestFieldConfigurationUpdate_Term(t *testing.T) {
+	assert := assert.New(t)
+	app := GetAppInstance()
+	var cfg = NewTagFieldConfiguration("tags", "content", "tagis")
+
+	t.Run("Should Return create a new term and associate", func(t *testing.T) {
+		modelId := "11"
+		terms := []string{"somethingnew1", "metoo2"}
+
+		err := cfg.Update(modelId, terms)
+		assert.Nil(err)
+
+		afterSaveTerms := []TermModel{}
+		err = cfg.FindManyTerm(modelId, &afterSaveTerms)
+		assert.Nil(err)
+		assert.Equal(2, len(afterSaveTerms))
+		assert.Equal(terms[0], afterSaveTerms[0].Text)
+		assert.Equal(terms[1], afterSaveTerms[1].Text)
+	})
+
+	t.Run("Should save lowercase terms", func(t *testing.T) {
+		modelId := "14"
+
+		err := cfg.Update(modelId, []string{"GoLang", " golang ", "Rust"})
+		assert.Nil(err)
+
+		afterSaveTerms := []TermModel{}
+		err = cfg.FindManyTerm(modelId, &afterSaveTerms)
+		assert.Nil(err)
+		assert.Equal(2, len(afterSaveTerms))
+		assert.Equal("golang", afterSaveTerms[0].Text)
+		assert.Equal("rust", afterSaveTerms[1].Text)
+	})
+
+	t.Run("Should remove 1 terms and add 2 new ones", func(t *testing.T) {
+		modelId := "12"
+		oldTerms := []string{"oldone1"}
+		terms := []string{"somethingnew2", "metoo3"}
+
+		err := cfg.Update(modelId, oldTerms)
+		assert.Nil(err)
+
+		afterSave1Terms := []TermModel{}
+		err = cfg.FindManyTerm(modelId, &afterSave1Terms)
+		assert.Nil(err)
+		assert.Equal(1, len(afterSave1Terms))
+		assert.Equal(oldTerms[0], afterSave1Terms[0].Text)
+
+		err = cfg.Update(modelId, terms)
+		assert.Nil(err)
+
+		afterSave2Terms := []TermModel{}
+		err = cfg.FindManyTerm(modelId, &afterSave2Terms)
+		assert.Nil(err)
+		assert.Equal(2, len(afterSave2Terms))
+		assert.Equal(terms[0], afterSave2Terms[0].Text)
+		assert.Equal(terms[1], afterSave2Terms[1].Text)
+	})
+
+	t.Run("Should remove 2, keep 2 terms and add 2 new ones", func(t *testing.T) {
+		modelId := "13"
+		oldTerms := []string{"oldone0", "oldone1", "oldone2", "oldone3"}
+		terms := []string{"oldone2", "oldone3", "somethingnew2", "metoo3"}
+
+		err := cfg.Update(modelId, oldTerms)
+		assert.Nil(err)
+
+		afterSave1Terms := []TermModel{}
+		err = cfg.FindManyTerm(modelId, &afterSave1Terms)
+		assert.Nil(err)
+
+		assert.Equal(4, len(afterSave1Terms))
+		assert.Equal(oldTerms[0], afterSave1Terms[0].Text)
+		assert.Equal(oldTerms[1], afterSave1Terms[1].Text)
+		assert.Equal(oldTerms[2], afterSave1Terms[2].Text)
+		assert.Equal(oldTerms[3], afterSave1Terms[3].Text)
+
+		err = cfg.Update(modelId, terms)
+		assert.Nil(err)
+
+		afterSave2Terms := []TermModel{}
+		err = cfg.FindManyTerm(modelId, &afterSave2Terms)
+		assert.Nil(err)
+
+		assert.Equal(4, len(afterSave2Terms))
+		assert.Equal(terms[0], afterSave2Terms[0].Text)
+		assert.Equal(terms[1], afterSave2Terms[1].Text)
+		assert.Equal(terms[2], afterSave2Terms[2].Text)
+		assert.Equal(terms[3], afterSave2Terms[3].Text)
+	})
+
+	t.Run("Should not create terms if the field cant create", func(t *testing.T) {
+		modelId := "15"
+		catCfg := NewCategoryFieldConfiguration("tags", "content", "tagis")
+		catCfg.SetFormFieldMultiple(true)
+
+		err := catCfg.Update(modelId, []string{"metoo3", "somethingUnknow2"})
+		assert.ErrorIs(err, ErrFieldCanNotCreateTerm)
+
+		afterSaveTerms := []TermModel{}
+		err = catCfg.FindManyTerm(modelId, &afterSaveTerms)
+		assert.Nil(err)
+		assert.Equal(0, len(afterSaveTerms))
+	})
+
+	t.Run("Should update the terms usage", func(t *testing.T) {
+		err := cfg.Update("17", []string{"usage1", "usage2"})
+		assert.Nil(err)
+		err = cfg.Update("18", []string{"usage1"})
+		assert.Nil(err)
+
+		terms := []TermModel{}
+		err = TermFindManyByText([]string{"usage1", "usage2"}, cfg.GetVocabularyName(), &terms)
+		assert.Nil(err)
+		assert.Equal(2, len(terms))
+		for _, term := range terms {
+			if term.Text == "usage1" {
+				assert.Equal(int64(2), term.Usage)
+			} else {
+				assert.Equal(int64(1), term.Usage)
+			}
+		}
+
+		err = cfg.Update("17", []string{"usage2"})
+		assert.Nil(err)
+		err = cfg.ClearField("18")
+		assert.Nil(err)
+
+		terms = []TermModel{}
+		err = TermFindManyByText([]string{"usage1", "usage2"}, cfg.GetVocabularyName(), &terms)
+		assert.Nil(err)
+		for _, term := range terms {
+			if term.Text == "usage1" {
+				assert.Equal(int64(0), term.Usage)
+			} else {
+				assert.Equal(int64(1), term.Usage)
+			}
+		}
+	})
+
+	t.Run("Should rollback the terms with the outer transaction", func(t *testing.T) {
+		modelId := "16"
+
+		err := app.GetDB().Transaction(func(tx *gorm.DB) error {
+			err := FieldWithDB(cfg, tx).Update(modelId, []string{"rollbackme1", "metoo3"})
+			assert.Nil(err)
+
+			return errors.New("rollback")
+		})
+		assert.NotNil(err)
+
+		afterSaveTerms := []TermModel{}
+		err = cfg.FindManyTerm(modelId, &afterSaveTerms)
+		assert.Nil(err)
+		assert.Equal(0, len(afterSaveTerms))
+
+		newTerm := TermModel{}
+		err = TermFindOneByText("rollbackme1", cfg.GetVocabularyName(), &newTerm)
+		assert.Nil(err)
+		assert.Equal(uint64(0), newTerm.ID)
+	})
+
+	t.Cleanup(func() {
+		db := app.GetDB()
+		r := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&ModelstermsModel{})
+		if r.Error != nil {
+			log.Println("Error on delete db modelsTerms", r.Error, r.RowsAffected)
+		}
+	})
+}
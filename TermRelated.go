@@ -0,0 +1,182 @@
+package tags
+
+import (
+	"context"
+	"math"
+
+	"github.com/go-catupiry/catu"
+	"github.com/pkg/errors"
+)
+
+// Max related terms returned in one request
+const TermRelatedMaxLimit = 50
+
+// TermRelatedItem - One term used in the same records of the source term
+type TermRelatedItem struct {
+	Term TermModel `json:"term"`
+	// Number of records with both terms
+	Count int64 `json:"count"`
+	// How much more often the terms appear together than if they were independent, > 1 is a positive association
+	Lift float64 `json:"lift"`
+	// Pointwise mutual information, log2(lift)
+	PMI float64 `json:"pmi"`
+}
+
+type TermRelatedOpts struct {
+	TermID uint64
+	// Only return terms of this vocabulary and only count records with terms of it, all vocabularies if empty
+	VocabularyName string
+	// Only count records of this model, all models if empty
+	ModelName string
+	// Load the terms translations in this locale if set
+	Locale string
+	// Defaults to 10, max TermRelatedMaxLimit
+	Limit int
+}
+
+type termCooccurrenceCount struct {
+	TermID uint64 `gorm:"column:termId"`
+	Count  int64  `gorm:"column:count"`
+}
+
+// Find the terms most often used in the same records (modelName and modelId) of the source term, with their
+// lift and PMI scores
+func TermFindRelated(opts *TermRelatedOpts, records *[]TermRelatedItem) error {
+	return TermFindRelatedContext(context.Background(), opts, records)
+}
+
+// TermFindRelatedContext - TermFindRelated using ctx in the database queries
+func TermFindRelatedContext(ctx context.Context, opts *TermRelatedOpts, records *[]TermRelatedItem) error {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > TermRelatedMaxLimit {
+		limit = TermRelatedMaxLimit
+	}
+
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	// distinct (termId, modelName, modelId) rows, one record may have the same term in more than one field
+	recordTerms := db.Model(&ModelstermsModel{}).
+		Distinct("termId", "modelName", "modelId").
+		Where("termId IS NOT NULL")
+	if opts.ModelName != "" {
+		recordTerms = recordTerms.Where("modelName = ?", opts.ModelName)
+	}
+
+	sourceRecords := db.Model(&ModelstermsModel{}).
+		Distinct("modelName", "modelId").
+		Where("termId = ?", opts.TermID)
+	if opts.ModelName != "" {
+		sourceRecords = sourceRecords.Where("modelName = ?", opts.ModelName)
+	}
+
+	var sourceCount int64
+	err := db.Table("(?) AS S", sourceRecords).Count(&sourceCount).Error
+	if err != nil {
+		return errors.Wrap(err, "TermFindRelated error on count source records")
+	}
+
+	if sourceCount == 0 {
+		return nil
+	}
+
+	allRecords := db.Model(&ModelstermsModel{}).
+		Distinct("modelName", "modelId").
+		Where("termId IS NOT NULL")
+	if opts.ModelName != "" {
+		allRecords = allRecords.Where("modelName = ?", opts.ModelName)
+	}
+	if opts.VocabularyName != "" {
+		allRecords = allRecords.Where("vocabularyName = ?", opts.VocabularyName)
+	}
+
+	var total int64
+	err = db.Table("(?) AS R", allRecords).Count(&total).Error
+	if err != nil {
+		return errors.Wrap(err, "TermFindRelated error on count records")
+	}
+
+	cooccurrences := db.Table("modelsterms AS M").
+		Distinct("M.termId", "M.modelName", "M.modelId").
+		Joins("INNER JOIN (?) AS S ON S.modelName = M.modelName AND S.modelId = M.modelId", sourceRecords).
+		Where("M.termId IS NOT NULL AND M.termId <> ?", opts.TermID)
+	if opts.VocabularyName != "" {
+		cooccurrences = cooccurrences.Where("M.vocabularyName = ?", opts.VocabularyName)
+	}
+
+	counts := []termCooccurrenceCount{}
+	err = db.Table("(?) AS C", cooccurrences).
+		Select("C.termId AS termId, COUNT(*) AS count").
+		Group("C.termId").
+		Order("count DESC").
+		Order("C.termId ASC").
+		Limit(limit).
+		Scan(&counts).Error
+	if err != nil {
+		return errors.Wrap(err, "TermFindRelated error on count co-occurrences")
+	}
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	ids := make([]uint64, len(counts))
+	for i := range counts {
+		ids[i] = counts[i].TermID
+	}
+
+	termTotals := []termCooccurrenceCount{}
+	err = db.Table("(?) AS T", recordTerms.Where("termId IN ?", ids)).
+		Select("T.termId AS termId, COUNT(*) AS count").
+		Group("T.termId").
+		Scan(&termTotals).Error
+	if err != nil {
+		return errors.Wrap(err, "TermFindRelated error on count term records")
+	}
+
+	totalsByID := map[uint64]int64{}
+	for _, t := range termTotals {
+		totalsByID[t.TermID] = t.Count
+	}
+
+	terms := []TermModel{}
+	err = db.Where("id IN ?", ids).Find(&terms).Error
+	if err != nil {
+		return errors.Wrap(err, "TermFindRelated error on find terms")
+	}
+
+	if opts.Locale != "" {
+		err = TermLoadManyLocalizedContext(ctx, terms, opts.Locale)
+		if err != nil {
+			return errors.Wrap(err, "TermFindRelated error on load terms translations")
+		}
+	}
+
+	termsByID := map[uint64]TermModel{}
+	for _, t := range terms {
+		termsByID[t.ID] = t
+	}
+
+	for _, c := range counts {
+		term, ok := termsByID[c.TermID]
+		if !ok {
+			continue
+		}
+
+		item := TermRelatedItem{
+			Term:  term,
+			Count: c.Count,
+		}
+
+		if totalsByID[c.TermID] > 0 {
+			item.Lift = float64(c.Count*total) / float64(sourceCount*totalsByID[c.TermID])
+			item.PMI = math.Log2(item.Lift)
+		}
+
+		*records = append(*records, item)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTermFindRelated(t *testing.T) {
+	assert := assert.New(t)
+	GetAppInstance()
+
+	cfg := NewTagFieldConfiguration("cooccurrence", "article", "tags")
+	assert.Nil(cfg.Update("1", []string{"go", "sql"}))
+	assert.Nil(cfg.Update("2", []string{"go", "sql", "web"}))
+	assert.Nil(cfg.Update("3", []string{"go", "web"}))
+	assert.Nil(cfg.Update("4", []string{"python", "web"}))
+
+	source := TermModel{}
+	assert.Nil(TermFindOneByText("go", "cooccurrence", &source))
+
+	records := []TermRelatedItem{}
+	err := TermFindRelated(&TermRelatedOpts{
+		TermID:         source.ID,
+		VocabularyName: "cooccurrence",
+	}, &records)
+	assert.Nil(err)
+	assert.Equal(2, len(records))
+	assert.Equal("sql", records[0].Term.Text)
+	assert.Equal(int64(2), records[0].Count)
+	// 2 * 4 / (3 * 2)
+	assert.InDelta(4.0/3.0, records[0].Lift, 0.001)
+	assert.Equal("web", records[1].Term.Text)
+	assert.InDelta(8.0/9.0, records[1].Lift, 0.001)
+	assert.Less(records[1].PMI, 0.0)
+}
@@ -0,0 +1,186 @@
+package tags
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"github.com/go-catupiry/catu"
+	"github.com/go-catupiry/catu/pagination"
+)
+
+// TermSEO - Search engine and social metadata of one term page, see NewTermSEO and HeadHTML
+type TermSEO struct {
+	Title       string
+	Description string
+	Locale      string
+	// Absolute URL of the current page, with the page param after the first page
+	Canonical string
+	// Previous and next pages of the term records, empty if there is no such page
+	PrevURL string
+	NextURL string
+	FeedURL string
+	// The term or its vocabulary is hidden from search engines
+	NoIndex bool
+	JSONLD  TermSEOCollectionPage
+}
+
+// TermSEOCollectionPage - schema.org CollectionPage of one term page
+type TermSEOCollectionPage struct {
+	Context     string             `json:"@context"`
+	Type        string             `json:"@type"`
+	ID          string             `json:"@id"`
+	URL         string             `json:"url"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	InLanguage  string             `json:"inLanguage,omitempty"`
+	MainEntity  TermSEODefinedTerm `json:"mainEntity"`
+}
+
+// TermSEODefinedTerm - schema.org DefinedTerm of one term
+type TermSEODefinedTerm struct {
+	Type             string                `json:"@type"`
+	ID               string                `json:"@id"`
+	URL              string                `json:"url"`
+	Name             string                `json:"name"`
+	Description      string                `json:"description,omitempty"`
+	TermCode         string                `json:"termCode,omitempty"`
+	InDefinedTermSet TermSEODefinedTermSet `json:"inDefinedTermSet"`
+}
+
+// TermSEODefinedTermSet - schema.org DefinedTermSet of one vocabulary
+type TermSEODefinedTermSet struct {
+	Type string `json:"@type"`
+	ID   string `json:"@id"`
+	URL  string `json:"url"`
+	Name string `json:"name"`
+}
+
+// Build the term page metadata. The term must have its locale and path loaded, the SEO title and description
+// overrides are only used in the default locale because they are not translated. The pager is used for the
+// canonical and rel prev / next URLs and may be nil
+func NewTermSEO(term *TermModel, vocabulary *VocabularyModel, locale string, pager *pagination.Pager) *TermSEO {
+	locale = NormalizeLocale(locale)
+	isDefaultLocale := locale == "" || locale == GetDefaultLocale()
+	if locale == "" {
+		locale = GetDefaultLocale()
+	}
+
+	seo := TermSEO{
+		Title:       term.GetLocalizedText(locale),
+		Description: term.GetLocalizedDescription(locale),
+		Locale:      locale,
+		Canonical:   term.LinkPermanent,
+		FeedURL:     catu.GetConfiguration().Get("APP_ORIGIN") + term.GetPath() + "/feed",
+		NoIndex:     term.NoIndex || vocabulary.NoIndex,
+	}
+
+	if isDefaultLocale && term.SEOTitle != "" {
+		seo.Title = term.SEOTitle
+	}
+	if isDefaultLocale && term.SEODescription != "" {
+		seo.Description = term.SEODescription
+	}
+
+	if pager != nil && pager.Limit > 0 {
+		pageCount := (pager.Count + pager.Limit - 1) / pager.Limit
+
+		if pager.Page > 1 {
+			seo.Canonical = termSEOPageURL(term.LinkPermanent, pager.Page)
+			seo.PrevURL = termSEOPageURL(term.LinkPermanent, pager.Page-1)
+		}
+		if pager.Page < pageCount {
+			seo.NextURL = termSEOPageURL(term.LinkPermanent, pager.Page+1)
+		}
+	}
+
+	vocabularyURL := catu.GetConfiguration().Get("APP_ORIGIN") + vocabulary.GetPath()
+	if !isDefaultLocale {
+		vocabularyURL = catu.GetConfiguration().Get("APP_ORIGIN") + "/" + locale + vocabulary.GetPath()
+	}
+
+	seo.JSONLD = TermSEOCollectionPage{
+		Context:     "https://schema.org",
+		Type:        "CollectionPage",
+		ID:          seo.Canonical,
+		URL:         seo.Canonical,
+		Name:        seo.Title,
+		Description: seo.Description,
+		InLanguage:  locale,
+		MainEntity: TermSEODefinedTerm{
+			Type:        "DefinedTerm",
+			ID:          term.LinkPermanent + "#term",
+			URL:         term.LinkPermanent,
+			Name:        term.GetLocalizedText(locale),
+			Description: term.GetLocalizedDescription(locale),
+			TermCode:    term.Slug,
+			InDefinedTermSet: TermSEODefinedTermSet{
+				Type: "DefinedTermSet",
+				ID:   vocabularyURL,
+				URL:  vocabularyURL,
+				Name: vocabulary.Name,
+			},
+		},
+	}
+
+	return &seo
+}
+
+func termSEOPageURL(url string, page int64) string {
+	if page <= 1 {
+		return url
+	}
+	return url + "?page=" + strconv.FormatInt(page, 10)
+}
+
+// HeadHTML - Canonical, robots, rel prev / next, feed, Open Graph, Twitter and JSON-LD tags for the page head
+func (r *TermSEO) HeadHTML() template.HTML {
+	var b strings.Builder
+
+	writeTag := func(format string, values ...string) {
+		args := make([]interface{}, len(values))
+		for i := range values {
+			args[i] = template.HTMLEscapeString(values[i])
+		}
+		fmt.Fprintf(&b, format+"\n", args...)
+	}
+
+	writeTag(`<link rel="canonical" href="%s">`, r.Canonical)
+	if r.NoIndex {
+		b.WriteString(`<meta name="robots" content="noindex, follow">` + "\n")
+	}
+	if r.PrevURL != "" {
+		writeTag(`<link rel="prev" href="%s">`, r.PrevURL)
+	}
+	if r.NextURL != "" {
+		writeTag(`<link rel="next" href="%s">`, r.NextURL)
+	}
+	if r.FeedURL != "" {
+		writeTag(`<link rel="alternate" type="application/rss+xml" title="%s" href="%s">`, r.Title, r.FeedURL)
+		writeTag(`<link rel="alternate" type="application/atom+xml" title="%s" href="%s">`, r.Title, r.FeedURL+"?format=atom")
+	}
+
+	writeTag(`<meta property="og:type" content="website">`)
+	writeTag(`<meta property="og:title" content="%s">`, r.Title)
+	if r.Description != "" {
+		writeTag(`<meta property="og:description" content="%s">`, r.Description)
+	}
+	writeTag(`<meta property="og:url" content="%s">`, r.Canonical)
+	writeTag(`<meta property="og:locale" content="%s">`, strings.Replace(r.Locale, "-", "_", -1))
+
+	writeTag(`<meta name="twitter:card" content="summary">`)
+	writeTag(`<meta name="twitter:title" content="%s">`, r.Title)
+	if r.Description != "" {
+		writeTag(`<meta name="twitter:description" content="%s">`, r.Description)
+	}
+
+	// json.Marshal escapes <, > and & so the data can not close the script tag
+	data, err := json.Marshal(r.JSONLD)
+	if err == nil {
+		b.WriteString(`<script type="application/ld+json">` + string(data) + "</script>\n")
+	}
+
+	return template.HTML(b.String())
+}
@@ -0,0 +1,59 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/go-catupiry/catu/pagination"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTermSEO(t *testing.T) {
+	assert := assert.New(t)
+	GetAppInstance()
+
+	term := TermModel{
+		ID:             1,
+		Text:           "golang",
+		Description:    "The go language",
+		Slug:           "golang",
+		VocabularyName: "seocategory",
+		SEOTitle:       "Go programming",
+	}
+	term.LoadPath()
+	vocabulary := VocabularyModel{Name: "seocategory"}
+
+	pager := pagination.NewPager()
+	pager.Page = 2
+	pager.Limit = 10
+	pager.Count = 25
+
+	seo := NewTermSEO(&term, &vocabulary, "", pager)
+	assert.Equal("Go programming", seo.Title)
+	assert.Equal("The go language", seo.Description)
+	assert.Equal(term.LinkPermanent+"?page=2", seo.Canonical)
+	assert.Equal(term.LinkPermanent, seo.PrevURL)
+	assert.Equal(term.LinkPermanent+"?page=3", seo.NextURL)
+	assert.False(seo.NoIndex)
+	assert.Equal("DefinedTerm", seo.JSONLD.MainEntity.Type)
+	assert.Equal("golang", seo.JSONLD.MainEntity.Name)
+	assert.Equal("seocategory", seo.JSONLD.MainEntity.InDefinedTermSet.Name)
+
+	head := string(seo.HeadHTML())
+	assert.Contains(head, `<link rel="canonical" href="`+term.LinkPermanent+`?page=2">`)
+	assert.Contains(head, `<meta property="og:title" content="Go programming">`)
+	assert.Contains(head, `<script type="application/ld+json">`)
+	assert.NotContains(head, `name="robots"`)
+
+	// last page, hidden vocabulary
+	pager.Page = 3
+	vocabulary.NoIndex = true
+	seo = NewTermSEO(&term, &vocabulary, "", pager)
+	assert.Empty(seo.NextURL)
+	assert.True(seo.NoIndex)
+	assert.Contains(string(seo.HeadHTML()), `<meta name="robots" content="noindex, follow">`)
+
+	// the overrides are not translated
+	seo = NewTermSEO(&term, &vocabulary, "pt-br", nil)
+	assert.Equal("golang", seo.Title)
+	assert.Equal(term.LinkPermanent, seo.Canonical)
+}
@@ -0,0 +1,139 @@
+package tags
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// HasTerms - GORM scope to filter the query model records associated with all terms.
+// Terms are term ids (int, int64, uint or uint64), texts (string, aliases included) or TermModel records.
+// Empty modelName, field or vocabularyName match any value and an empty terms list does not filter.
+// Works with the query returned by ctx.Query.SetDatabaseQueryForModel, ex:
+//
+//	query.(*gorm.DB).Scopes(tags.HasTerms("content", "tags", "Tags", "go", "sql")).Find(&contents)
+func HasTerms(modelName, field, vocabularyName string, terms ...interface{}) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, term := range terms {
+			db = db.Where("EXISTS (?)", termScopeSubquery(db, termScopeModelID(db), modelName, field, vocabularyName, []interface{}{term}))
+		}
+		return db
+	}
+}
+
+// HasAnyTerm - GORM scope to filter the query model records associated with at least one of the terms,
+// see HasTerms
+func HasAnyTerm(modelName, field, vocabularyName string, terms ...interface{}) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(terms) == 0 {
+			return db
+		}
+		return db.Where("EXISTS (?)", termScopeSubquery(db, termScopeModelID(db), modelName, field, vocabularyName, terms))
+	}
+}
+
+// LacksTerms - GORM scope to filter the query model records not associated with any of the terms, see HasTerms
+func LacksTerms(modelName, field, vocabularyName string, terms ...interface{}) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(terms) == 0 {
+			return db
+		}
+		return db.Where("NOT EXISTS (?)", termScopeSubquery(db, termScopeModelID(db), modelName, field, vocabularyName, terms))
+	}
+}
+
+// Build the modelsterms subquery matching the modelID record and one of the terms.
+// modelName is one model name, "" for any model, or one column with the record model name
+func termScopeSubquery(db *gorm.DB, modelID clause.Column, modelName interface{}, field, vocabularyName string, terms []interface{}) *gorm.DB {
+	query := db.Session(&gorm.Session{NewDB: true}).
+		Table("modelsterms AS MT").
+		Select("1").
+		Where("MT.modelId = ?", modelID)
+
+	if name, ok := modelName.(string); !ok || name != "" {
+		query = query.Where("MT.modelName = ?", modelName)
+	}
+	if field != "" {
+		query = query.Where("MT.field = ?", field)
+	}
+	if vocabularyName != "" {
+		query = query.Where("MT.vocabularyName = ?", vocabularyName)
+	}
+
+	var ids []uint64
+	var texts []string
+
+	for _, term := range terms {
+		switch v := term.(type) {
+		case string:
+			texts = append(texts, v)
+		case TermModel:
+			ids = append(ids, v.ID)
+		case *TermModel:
+			ids = append(ids, v.ID)
+		case int:
+			ids = append(ids, uint64(v))
+		case int64:
+			ids = append(ids, uint64(v))
+		case uint:
+			ids = append(ids, uint64(v))
+		case uint64:
+			ids = append(ids, v)
+		default:
+			db.AddError(errors.Errorf("tags term scope: invalid term type %T", term))
+		}
+	}
+
+	conditions := db.Session(&gorm.Session{NewDB: true})
+
+	if len(ids) > 0 {
+		conditions = conditions.Or("MT.termId IN ?", ids)
+	}
+
+	if len(texts) > 0 {
+		termIDs := db.Session(&gorm.Session{NewDB: true}).
+			Model(&TermModel{}).
+			Select("id").
+			Where("text IN ?", texts)
+		aliasTermIDs := db.Session(&gorm.Session{NewDB: true}).
+			Model(&TermAliasModel{}).
+			Select("termId").
+			Where("text IN ?", texts)
+
+		if vocabularyName != "" {
+			termIDs = termIDs.Where("vocabularyName = ?", vocabularyName)
+			aliasTermIDs = aliasTermIDs.Where("vocabularyName = ?", vocabularyName)
+		}
+
+		conditions = conditions.
+			Or("MT.termId IN (?)", termIDs).
+			Or("MT.termId IN (?)", aliasTermIDs)
+	}
+
+	return query.Where(conditions)
+}
+
+// Get the primary key column of the query model, scopes run before gorm parses the model
+func termScopeModelID(db *gorm.DB) clause.Column {
+	stmt := db.Statement
+
+	model := stmt.Model
+	if model == nil {
+		model = stmt.Dest
+	}
+
+	if stmt.Schema == nil && model != nil {
+		err := stmt.Parse(model)
+		if err != nil && !errors.Is(err, schema.ErrUnsupportedDataType) {
+			db.AddError(err)
+		}
+	}
+
+	name := "id"
+	if stmt.Schema != nil && stmt.Schema.PrioritizedPrimaryField != nil {
+		name = stmt.Schema.PrioritizedPrimaryField.DBName
+	}
+
+	return clause.Column{Table: stmt.Table, Name: name}
+}
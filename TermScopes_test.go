@@ -0,0 +1,52 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTermScopes(t *testing.T) {
+	assert := assert.New(t)
+
+	app := GetAppInstance()
+	db := app.GetDB()
+
+	records := []TaxonomyContentStub{{Title: "A"}, {Title: "B"}, {Title: "C"}}
+	assert.Nil(db.Create(&records).Error)
+
+	cfg := NewTagFieldConfiguration("scopetags", "taxonomy_content_stubs", "scopetags")
+	assert.Nil(cfg.Update(records[0].GetIDString(), []string{"go", "sql"}))
+	assert.Nil(cfg.Update(records[1].GetIDString(), []string{"go"}))
+	assert.Nil(cfg.Update(records[2].GetIDString(), []string{"rust"}))
+
+	sql := TermModel{}
+	assert.Nil(TermFindOneByText("sql", "scopetags", &sql))
+
+	find := func(scopes ...func(*gorm.DB) *gorm.DB) []string {
+		list := []TaxonomyContentStub{}
+		err := db.Scopes(scopes...).
+			Where("id IN ?", []uint64{records[0].ID, records[1].ID, records[2].ID}).
+			Order("id ASC").
+			Find(&list).Error
+		assert.Nil(err)
+
+		titles := []string{}
+		for _, r := range list {
+			titles = append(titles, r.Title)
+		}
+		return titles
+	}
+
+	assert.Equal([]string{"A"}, find(HasTerms("taxonomy_content_stubs", "scopetags", "scopetags", "go", sql.ID)))
+	assert.Equal([]string{"A", "C"}, find(HasAnyTerm("", "", "scopetags", "sql", "rust")))
+	assert.Equal([]string{"B"}, find(HasTerms("", "", "scopetags", "go"), LacksTerms("", "", "scopetags", &sql)))
+
+	var count int64
+	err := db.Model(&TaxonomyContentStub{}).Scopes(LacksTerms("", "", "scopetags", "go")).
+		Where("id IN ?", []uint64{records[0].ID, records[1].ID, records[2].ID}).
+		Count(&count).Error
+	assert.Nil(err)
+	assert.Equal(int64(1), count)
+}
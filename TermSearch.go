@@ -0,0 +1,517 @@
+package tags
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/go-catupiry/catu"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Markers used by the database highlight functions, replaced by <mark> after the html escape
+const (
+	termSearchMarkStart = "\x02"
+	termSearchMarkEnd   = "\x03"
+)
+
+// Words around the first match in the description snippets
+const termSearchSnippetWords = 16
+
+// TermSearchBackend - Full-text index of the terms text and description
+type TermSearchBackend interface {
+	// Backend name, ex: fts5
+	Name() string
+	// Create the index if it does not exists, runs before and after the app migrations
+	Setup(db *gorm.DB) error
+	// Add or update one term in the index
+	Index(db *gorm.DB, record *TermModel) error
+	// Remove one term from the index
+	Remove(db *gorm.DB, termID uint64) error
+	// Condition to filter the terms table with the q text
+	Where(db *gorm.DB, q string) *gorm.DB
+	// Find terms matching the q text sorted by relevance, only ID and Score are required in the results
+	Search(db *gorm.DB, opts *TermSearchOpts, results *[]TermSearchResult) error
+}
+
+type TermSearchOpts struct {
+	VocabularyName string
+	Q              string
+	Limit          int
+	Offset         int
+}
+
+// TermSearchResult - One term found in the search with its highlighted text and description snippet
+type TermSearchResult struct {
+	ID    uint64  `gorm:"column:id" json:"-"`
+	Score float64 `gorm:"column:score" json:"score"`
+	// Term text with the matches inside <mark> tags, html escaped
+	Highlight string `gorm:"column:highlight" json:"highlight"`
+	// Part of the description around the matches inside <mark> tags, html escaped
+	Snippet string     `gorm:"column:snippet" json:"snippet"`
+	Record  *TermModel `gorm:"-" json:"term"`
+}
+
+var termSearchBackend TermSearchBackend = &TermLikeSearchBackend{}
+
+// Get the current search backend, set in TermSearchSetup
+func GetTermSearchBackend() TermSearchBackend {
+	return termSearchBackend
+}
+
+// Set the search backend, use it to plug other search backends
+func SetTermSearchBackend(backend TermSearchBackend) {
+	termSearchBackend = backend
+}
+
+// Select and setup the search backend for the db driver: FTS5 for sqlite and FULLTEXT for mysql.
+// The LIKE backend is used if the driver index is not available, ex: sqlite built without the sqlite_fts5 tag
+func TermSearchSetup(db *gorm.DB) error {
+	var backend TermSearchBackend
+
+	switch db.Dialector.Name() {
+	case "sqlite":
+		backend = &TermFTS5SearchBackend{}
+	case "mysql":
+		backend = &TermMySQLSearchBackend{}
+	default:
+		backend = &TermLikeSearchBackend{}
+	}
+
+	err := backend.Setup(db)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"backend": backend.Name(),
+			"error":   err,
+		}).Warn("TermSearchSetup full-text index not available, using the LIKE search")
+
+		backend = &TermLikeSearchBackend{}
+	}
+
+	SetTermSearchBackend(backend)
+
+	return nil
+}
+
+// AfterSave - Update the term in the search index, skipped in updates without one term, ex: db.Model(&TermModel{}).Where(...).Update(...)
+func (r *TermModel) AfterSave(tx *gorm.DB) error {
+	if r.ID == 0 {
+		return nil
+	}
+
+	return GetTermSearchBackend().Index(tx, r)
+}
+
+// AfterDelete - Remove the term from the search index
+func (r *TermModel) AfterDelete(tx *gorm.DB) error {
+	if r.ID == 0 {
+		return nil
+	}
+
+	return GetTermSearchBackend().Remove(tx, r.ID)
+}
+
+// Find terms by text and description sorted by relevance, with highlighted texts and snippets
+func TermSearch(opts *TermSearchOpts, results *[]TermSearchResult, count *int64) error {
+	return TermSearchContext(context.Background(), opts, results, count)
+}
+
+// TermSearchContext - TermSearch using ctx in the database queries
+func TermSearchContext(ctx context.Context, opts *TermSearchOpts, results *[]TermSearchResult, count *int64) error {
+	if len(termSearchTokens(opts.Q)) == 0 {
+		return nil
+	}
+
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+	backend := GetTermSearchBackend()
+
+	err := backend.Search(db, opts, results)
+	if err != nil {
+		return errors.Wrap(err, "TermSearch error on search with "+backend.Name())
+	}
+
+	countQuery := db.Model(&TermModel{}).Where(backend.Where(db, opts.Q))
+	if opts.VocabularyName != "" {
+		countQuery = countQuery.Where("vocabularyName = ?", opts.VocabularyName)
+	}
+
+	err = countQuery.Count(count).Error
+	if err != nil {
+		return errors.Wrap(err, "TermSearch error on count")
+	}
+
+	ids := []uint64{}
+	for i := range *results {
+		ids = append(ids, (*results)[i].ID)
+	}
+
+	records := []TermModel{}
+	if len(ids) > 0 {
+		err = db.Where("id IN ?", ids).Find(&records).Error
+		if err != nil {
+			return errors.Wrap(err, "TermSearch error on find terms")
+		}
+	}
+
+	re := termSearchRegexp(opts.Q)
+	found := []TermSearchResult{}
+
+	for _, result := range *results {
+		for j := range records {
+			if records[j].ID != result.ID {
+				continue
+			}
+
+			records[j].LoadTeaserData()
+			result.Record = &records[j]
+
+			// database highlights are marked with the markers, others are highlighted here
+			if result.Highlight == "" {
+				result.Highlight = termSearchMark(records[j].Text, re)
+			} else {
+				result.Highlight = termSearchMarkersToHTML(result.Highlight)
+			}
+
+			if result.Snippet == "" {
+				result.Snippet = termSearchSnippet(records[j].Description, re)
+			} else {
+				result.Snippet = termSearchMarkersToHTML(result.Snippet)
+			}
+
+			found = append(found, result)
+			break
+		}
+	}
+
+	*results = found
+
+	return nil
+}
+
+// Split the search text in words, removing operators and punctuation
+func termSearchTokens(q string) []string {
+	return strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func termSearchRegexp(q string) *regexp.Regexp {
+	tokens := termSearchTokens(q)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	for i := range tokens {
+		tokens[i] = regexp.QuoteMeta(tokens[i])
+	}
+
+	return regexp.MustCompile("(?i)(" + strings.Join(tokens, "|") + ")")
+}
+
+// Escape the text and wrap the re matches in <mark> tags
+func termSearchMark(text string, re *regexp.Regexp) string {
+	if re == nil {
+		return html.EscapeString(text)
+	}
+
+	return termSearchMarkersToHTML(re.ReplaceAllString(text, termSearchMarkStart+"${1}"+termSearchMarkEnd))
+}
+
+func termSearchMarkersToHTML(text string) string {
+	text = html.EscapeString(text)
+	text = strings.ReplaceAll(text, termSearchMarkStart, "<mark>")
+	return strings.ReplaceAll(text, termSearchMarkEnd, "</mark>")
+}
+
+// Get the description words around the first match, highlighted
+func termSearchSnippet(description string, re *regexp.Regexp) string {
+	words := strings.Fields(description)
+	if len(words) <= termSearchSnippetWords {
+		return termSearchMark(description, re)
+	}
+
+	first := 0
+	if re != nil {
+		for i := range words {
+			if re.MatchString(words[i]) {
+				first = i
+				break
+			}
+		}
+	}
+
+	start := first - termSearchSnippetWords/2
+	if start < 0 {
+		start = 0
+	}
+
+	end := start + termSearchSnippetWords
+	if end > len(words) {
+		end = len(words)
+		start = end - termSearchSnippetWords
+	}
+
+	snippet := termSearchMark(strings.Join(words[start:end], " "), re)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(words) {
+		snippet += "…"
+	}
+
+	return snippet
+}
+
+// TermLikeSearchBackend - Search with LIKE, without index and with a simple relevance: text matches first
+type TermLikeSearchBackend struct{}
+
+func (b *TermLikeSearchBackend) Name() string {
+	return "like"
+}
+
+func (b *TermLikeSearchBackend) Setup(db *gorm.DB) error {
+	return nil
+}
+
+func (b *TermLikeSearchBackend) Index(db *gorm.DB, record *TermModel) error {
+	return nil
+}
+
+func (b *TermLikeSearchBackend) Remove(db *gorm.DB, termID uint64) error {
+	return nil
+}
+
+func (b *TermLikeSearchBackend) Where(db *gorm.DB, q string) *gorm.DB {
+	return db.Where("text LIKE ?", "%"+q+"%").
+		Or(db.Where("description LIKE ?", "%"+q+"%"))
+}
+
+func (b *TermLikeSearchBackend) Search(db *gorm.DB, opts *TermSearchOpts, results *[]TermSearchResult) error {
+	query := db.Model(&TermModel{}).
+		Select("id, CASE WHEN text LIKE ? THEN 2 ELSE 1 END AS score", "%"+opts.Q+"%").
+		Where(b.Where(db, opts.Q))
+
+	if opts.VocabularyName != "" {
+		query = query.Where("vocabularyName = ?", opts.VocabularyName)
+	}
+
+	return query.Order("score DESC").
+		Order("usageCount DESC").
+		Order("id ASC").
+		Limit(opts.Limit).
+		Offset(opts.Offset).
+		Find(results).Error
+}
+
+// TermFTS5SearchBackend - SQLite FTS5 index in the terms_fts table, requires sqlite built with FTS5
+type TermFTS5SearchBackend struct{}
+
+func (b *TermFTS5SearchBackend) Name() string {
+	return "fts5"
+}
+
+// Create the index and rebuild it if it is out of date with the terms table, ex: terms saved before the index or
+// one setup run before the terms table migration. The table creation and the rebuild run in one transaction
+func (b *TermFTS5SearchBackend) Setup(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS terms_fts USING fts5(" +
+			"text, description, vocabularyName UNINDEXED, tokenize = 'unicode61 remove_diacritics 2')").Error
+		if err != nil {
+			return err
+		}
+
+		// the terms table is created in the app migrations, the index is filled in the next setup
+		if !tx.Migrator().HasTable(&TermModel{}) {
+			return nil
+		}
+
+		outdated, err := b.isOutdated(tx)
+		if err != nil || !outdated {
+			return err
+		}
+
+		return b.Rebuild(tx)
+	})
+}
+
+// Check if the index has other terms than the terms table
+func (b *TermFTS5SearchBackend) isOutdated(db *gorm.DB) (bool, error) {
+	var terms, indexed, missing int64
+
+	err := db.Model(&TermModel{}).Count(&terms).Error
+	if err != nil {
+		return false, err
+	}
+
+	err = db.Table("terms_fts").Count(&indexed).Error
+	if err != nil {
+		return false, err
+	}
+
+	if terms != indexed {
+		return true, nil
+	}
+
+	err = db.Model(&TermModel{}).
+		Where("id NOT IN (SELECT rowid FROM terms_fts)").
+		Count(&missing).Error
+	if err != nil {
+		return false, err
+	}
+
+	return missing > 0, nil
+}
+
+// Rebuild - Index all terms again, use it after changing the terms table without the model hooks
+func (b *TermFTS5SearchBackend) Rebuild(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("DELETE FROM terms_fts").Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec("INSERT INTO terms_fts(rowid, text, description, vocabularyName) " +
+			"SELECT id, text, COALESCE(description, ''), vocabularyName FROM terms").Error
+	})
+}
+
+func (b *TermFTS5SearchBackend) Index(db *gorm.DB, record *TermModel) error {
+	db = db.Session(&gorm.Session{NewDB: true})
+
+	err := b.Remove(db, record.ID)
+	if err != nil {
+		return err
+	}
+
+	return db.Exec("INSERT INTO terms_fts(rowid, text, description, vocabularyName) VALUES (?, ?, ?, ?)",
+		record.ID, record.Text, record.Description, record.VocabularyName).Error
+}
+
+func (b *TermFTS5SearchBackend) Remove(db *gorm.DB, termID uint64) error {
+	return db.Session(&gorm.Session{NewDB: true}).
+		Exec("DELETE FROM terms_fts WHERE rowid = ?", termID).Error
+}
+
+// Build the FTS5 query with each word as a prefix, ex: go orm -> "go"* "orm"*
+func (b *TermFTS5SearchBackend) match(q string) string {
+	tokens := termSearchTokens(q)
+	for i := range tokens {
+		tokens[i] = `"` + strings.ReplaceAll(tokens[i], `"`, `""`) + `"*`
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+func (b *TermFTS5SearchBackend) Where(db *gorm.DB, q string) *gorm.DB {
+	match := b.match(q)
+	if match == "" {
+		return (&TermLikeSearchBackend{}).Where(db, q)
+	}
+
+	return db.Where("id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+		Table("terms_fts").
+		Select("rowid").
+		Where("terms_fts MATCH ?", match))
+}
+
+func (b *TermFTS5SearchBackend) Search(db *gorm.DB, opts *TermSearchOpts, results *[]TermSearchResult) error {
+	query := db.Table("terms_fts").
+		Select("terms_fts.rowid AS id, -bm25(terms_fts) AS score, "+
+			"highlight(terms_fts, 0, ?, ?) AS highlight, "+
+			"snippet(terms_fts, 1, ?, ?, '…', ?) AS snippet",
+			termSearchMarkStart, termSearchMarkEnd, termSearchMarkStart, termSearchMarkEnd, termSearchSnippetWords).
+		Joins("INNER JOIN terms ON terms.id = terms_fts.rowid").
+		Where("terms_fts MATCH ?", b.match(opts.Q))
+
+	if opts.VocabularyName != "" {
+		query = query.Where("terms.vocabularyName = ?", opts.VocabularyName)
+	}
+
+	return query.Order("bm25(terms_fts)").
+		Limit(opts.Limit).
+		Offset(opts.Offset).
+		Scan(results).Error
+}
+
+// TermMySQLSearchBackend - MySQL FULLTEXT index on the terms text and description
+type TermMySQLSearchBackend struct {
+	// Words smaller than this are not indexed, the InnoDB innodb_ft_min_token_size default is 3
+	MinTokenSize int
+}
+
+const termMySQLFullTextIndex = "terms_text_description_FTIDX"
+
+func (b *TermMySQLSearchBackend) Name() string {
+	return "mysql_fulltext"
+}
+
+func (b *TermMySQLSearchBackend) Setup(db *gorm.DB) error {
+	if db.Migrator().HasIndex(&TermModel{}, termMySQLFullTextIndex) {
+		return nil
+	}
+
+	return db.Exec("ALTER TABLE terms ADD FULLTEXT INDEX " + termMySQLFullTextIndex + " (text, description)").Error
+}
+
+// The FULLTEXT index is updated by MySQL
+func (b *TermMySQLSearchBackend) Index(db *gorm.DB, record *TermModel) error {
+	return nil
+}
+
+func (b *TermMySQLSearchBackend) Remove(db *gorm.DB, termID uint64) error {
+	return nil
+}
+
+// Build the boolean mode query requiring each word as a prefix, ex: go orm -> +go* +orm*.
+// Returns an empty text if one word is smaller than the MinTokenSize
+func (b *TermMySQLSearchBackend) against(q string) string {
+	minTokenSize := b.MinTokenSize
+	if minTokenSize == 0 {
+		minTokenSize = 3
+	}
+
+	tokens := termSearchTokens(q)
+	for i := range tokens {
+		if len([]rune(tokens[i])) < minTokenSize {
+			return ""
+		}
+
+		tokens[i] = "+" + tokens[i] + "*"
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+func (b *TermMySQLSearchBackend) Where(db *gorm.DB, q string) *gorm.DB {
+	against := b.against(q)
+	if against == "" {
+		return (&TermLikeSearchBackend{}).Where(db, q)
+	}
+
+	return db.Where("MATCH(text, description) AGAINST (? IN BOOLEAN MODE)", against)
+}
+
+func (b *TermMySQLSearchBackend) Search(db *gorm.DB, opts *TermSearchOpts, results *[]TermSearchResult) error {
+	against := b.against(opts.Q)
+	if against == "" {
+		return (&TermLikeSearchBackend{}).Search(db, opts, results)
+	}
+
+	query := db.Model(&TermModel{}).
+		Select("id, MATCH(text, description) AGAINST (? IN BOOLEAN MODE) AS score", against).
+		Where(b.Where(db, opts.Q))
+
+	if opts.VocabularyName != "" {
+		query = query.Where("vocabularyName = ?", opts.VocabularyName)
+	}
+
+	return query.Order("score DESC").
+		Order("id ASC").
+		Limit(opts.Limit).
+		Offset(opts.Offset).
+		Find(results).Error
+}
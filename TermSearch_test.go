@@ -0,0 +1,132 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// The FTS5 backend is only used with sqlite built with FTS5, run the tests with: go test -tags sqlite_fts5 ./...
+func sqliteHasFTS5(db *gorm.DB) bool {
+	var used int
+	db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&used)
+	return used == 1
+}
+
+func TestTermSearch(t *testing.T) {
+	assert := assert.New(t)
+	GetAppInstance()
+
+	vocabularyName := "search"
+	terms := map[string]string{
+		"Golang": "A programming language created at Google",
+		"Gorm":   "The <b>ORM</b> library for Golang",
+		"Rust":   "Another programming language",
+	}
+	for text, description := range terms {
+		term := TermModel{Text: text, Description: description, VocabularyName: vocabularyName}
+		err := term.Save()
+		assert.Nil(err)
+	}
+
+	var count int64
+	results := []TermSearchResult{}
+	err := TermSearch(&TermSearchOpts{VocabularyName: vocabularyName, Q: "golang", Limit: 10}, &results, &count)
+	assert.Nil(err)
+	assert.Equal(int64(2), count)
+	assert.Equal(2, len(results))
+
+	for _, r := range results {
+		assert.NotNil(r.Record)
+		if r.Record.Text == "Golang" {
+			assert.Equal("<mark>Golang</mark>", r.Highlight)
+		} else {
+			assert.Equal("The &lt;b&gt;ORM&lt;/b&gt; library for <mark>Golang</mark>", r.Snippet)
+		}
+	}
+}
+
+func TestTermSearchBackend(t *testing.T) {
+	app := GetAppInstance()
+
+	if sqliteHasFTS5(app.GetDB()) {
+		assert.Equal(t, "fts5", GetTermSearchBackend().Name())
+	} else {
+		assert.Equal(t, "like", GetTermSearchBackend().Name())
+	}
+}
+
+func TestTermFTS5SearchBackend_Setup(t *testing.T) {
+	assert := assert.New(t)
+
+	// one new database, the plugin setup runs before the app migrations
+	db, err := gorm.Open(sqlite.Open("file:ftssetup?mode=memory&cache=shared"), &gorm.Config{})
+	assert.Nil(err)
+	if !sqliteHasFTS5(db) {
+		t.Skip("sqlite built without FTS5, use the sqlite_fts5 build tag")
+	}
+
+	b := &TermFTS5SearchBackend{}
+	assert.Nil(b.Setup(db))
+	assert.True(db.Migrator().HasTable("terms_fts"))
+
+	assert.Nil(db.AutoMigrate(&TermModel{}))
+	// terms saved without the index hooks
+	terms := []TermModel{
+		{Text: "Golang", Slug: "golang", VocabularyName: "ftssetup"},
+		{Text: "Rust", Slug: "rust", VocabularyName: "ftssetup"},
+	}
+	assert.Nil(db.Session(&gorm.Session{SkipHooks: true}).Create(&terms).Error)
+
+	countIndexed := func() int64 {
+		var count int64
+		assert.Nil(db.Table("terms_fts").Count(&count).Error)
+		return count
+	}
+	assert.Equal(int64(0), countIndexed())
+
+	// the next setup rebuilds the empty index
+	assert.Nil(b.Setup(db))
+	assert.Equal(int64(2), countIndexed())
+
+	results := []TermSearchResult{}
+	assert.Nil(b.Search(db, &TermSearchOpts{Q: "golang", Limit: 10}, &results))
+	assert.Equal(1, len(results))
+	assert.Equal(terms[0].ID, results[0].ID)
+
+	// and one outdated index
+	assert.Nil(db.Exec("DELETE FROM terms_fts WHERE rowid = ?", terms[1].ID).Error)
+	assert.Nil(db.Exec("INSERT INTO terms_fts(rowid, text, description, vocabularyName) VALUES (?, ?, ?, ?)", 1000, "Zig", "", "ftssetup").Error)
+	assert.Nil(b.Setup(db))
+	assert.Equal(int64(2), countIndexed())
+
+	results = []TermSearchResult{}
+	assert.Nil(b.Search(db, &TermSearchOpts{Q: "rust", Limit: 10}, &results))
+	assert.Equal(1, len(results))
+}
+
+func TestTermFTS5SearchBackend_BulkUpdates(t *testing.T) {
+	assert := assert.New(t)
+	db := GetAppInstance().GetDB()
+	if !sqliteHasFTS5(db) {
+		t.Skip("sqlite built without FTS5, use the sqlite_fts5 build tag")
+	}
+
+	root, child, _, _ := saveTermTreeStub(assert, "ftsbulk")
+	assert.Nil(root.Delete())
+
+	target := TermModel{Text: "target", VocabularyName: "ftsbulk"}
+	assert.Nil(target.Save())
+	assert.Nil(TermMerge(&child, &target))
+
+	// bulk updates without one term do not index empty rows
+	var count int64
+	assert.Nil(db.Table("terms_fts").Where("rowid = 0 OR text = ''").Count(&count).Error)
+	assert.Equal(int64(0), count)
+
+	results := []TermSearchResult{}
+	assert.Nil(GetTermSearchBackend().Search(db, &TermSearchOpts{VocabularyName: "ftsbulk", Q: "grandchild", Limit: 10}, &results))
+	assert.Equal(1, len(results))
+}
@@ -0,0 +1,264 @@
+package tags
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-catupiry/catu"
+	"github.com/gosimple/slug"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// TermSlugRedirectModel - Stores old term slugs, used to redirect old urls after one term rename
+type TermSlugRedirectModel struct {
+	ID             uint64    `gorm:"primaryKey;column:id" json:"id"`
+	TermID         uint64    `gorm:"index:term_slug_redirects_termId_IDX;column:termId;type:int(11);not null" json:"termId"`
+	Slug           string    `gorm:"index:term_slug_redirects_slug_IDX;column:slug;type:varchar(255);not null" json:"slug"`
+	VocabularyName string    `gorm:"index:term_slug_redirects_slug_IDX;column:vocabularyName;type:varchar(255);not null;default:Tags" json:"vocabularyName"`
+	CreatedAt      time.Time `gorm:"column:createdAt;type:datetime;not null" json:"createdAt"`
+	UpdatedAt      time.Time `gorm:"column:updatedAt;type:datetime;not null" json:"updatedAt"`
+}
+
+// TableName - Set db table name for term slug redirect model
+func (r *TermSlugRedirectModel) TableName() string {
+	return "term_slug_redirects"
+}
+
+// Find One term by vocabulary / slug
+func TermFindOneBySlug(termSlug, vocabularyName string, record *TermModel) error {
+	return TermFindOneBySlugContext(context.Background(), termSlug, vocabularyName, record)
+}
+
+// TermFindOneBySlugContext - TermFindOneBySlug using ctx in the database queries
+func TermFindOneBySlugContext(ctx context.Context, termSlug, vocabularyName string, record *TermModel) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	return db.Where("slug = ? AND vocabularyName = ?", termSlug, vocabularyName).
+		Limit(1).
+		Find(record).Error
+}
+
+// Find the term that used the slug before one rename, record.ID will be 0 if not found
+func TermFindOneByOldSlug(termSlug, vocabularyName string, record *TermModel) error {
+	return TermFindOneByOldSlugContext(context.Background(), termSlug, vocabularyName, record)
+}
+
+// TermFindOneByOldSlugContext - TermFindOneByOldSlug using ctx in the database queries
+func TermFindOneByOldSlugContext(ctx context.Context, termSlug, vocabularyName string, record *TermModel) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	redirect := TermSlugRedirectModel{}
+	err := db.Where("slug = ? AND vocabularyName = ?", termSlug, vocabularyName).
+		Order("id DESC").
+		Limit(1).
+		Find(&redirect).Error
+	if err != nil {
+		return err
+	}
+
+	if redirect.ID == 0 {
+		return nil
+	}
+
+	return db.Where("id = ?", redirect.TermID).
+		Limit(1).
+		Find(record).Error
+}
+
+// Generate slugs for all terms without one or with one all-digit slug, use it to update terms created before
+// the slug support
+func TermEnsureSlugs() error {
+	return TermEnsureSlugsContext(context.Background())
+}
+
+// TermEnsureSlugsContext - TermEnsureSlugs using ctx in the database queries
+func TermEnsureSlugsContext(ctx context.Context) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	slugs := []TermModel{}
+	err := db.Select("id", "slug").Find(&slugs).Error
+	if err != nil {
+		return errors.Wrap(err, "TermEnsureSlugs error on find slugs")
+	}
+
+	ids := []uint64{}
+	for i := range slugs {
+		if slugs[i].Slug == "" || termSlugIsNumeric(slugs[i].Slug) {
+			ids = append(ids, slugs[i].ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	records := []TermModel{}
+	err = db.Where("id IN ?", ids).Find(&records).Error
+	if err != nil {
+		return errors.Wrap(err, "TermEnsureSlugs error on find terms")
+	}
+
+	for i := range records {
+		// all-digit slugs are generated again from the text
+		records[i].Slug = ""
+
+		err = records[i].setSlug(db)
+		if err != nil {
+			return err
+		}
+
+		err = db.Model(&records[i]).Update("slug", records[i].Slug).Error
+		if err != nil {
+			return errors.Wrap(err, "TermEnsureSlugs error on update term slug")
+		}
+	}
+
+	return nil
+}
+
+// Set one unique slug before save. New slugs are generated from the text on create, on text change
+// if the slug was not changed together and when the slug is empty.
+// Replaced slugs are stored to redirect old term urls
+func (r *TermModel) setSlug(db *gorm.DB) error {
+	old := TermModel{}
+	if r.ID != 0 {
+		err := db.Select("id", "text", "slug", "vocabularyName").
+			Where("id = ?", r.ID).
+			Limit(1).
+			Find(&old).Error
+		if err != nil {
+			return errors.Wrap(err, "TermModel.setSlug error on find saved term")
+		}
+	}
+
+	base := r.Slug
+	if base == "" || (old.ID != 0 && old.Text != r.Text && old.Slug == r.Slug) {
+		base = r.Text
+	}
+
+	base = termSlugMake(base)
+
+	// keep the saved slug if it is still valid
+	if old.ID != 0 && old.Slug == base && old.VocabularyName == r.VocabularyName {
+		r.Slug = old.Slug
+		return nil
+	}
+
+	newSlug, err := termUniqueSlug(db, base, r.VocabularyName, r.ID, nil)
+	if err != nil {
+		return err
+	}
+
+	r.Slug = newSlug
+
+	if old.ID == 0 || old.Slug == "" || old.Slug == r.Slug {
+		return nil
+	}
+
+	err = db.Where("termId = ? AND slug = ?", r.ID, r.Slug).
+		Delete(&TermSlugRedirectModel{}).Error
+	if err != nil {
+		return errors.Wrap(err, "TermModel.setSlug error on delete reused redirect")
+	}
+
+	redirect := TermSlugRedirectModel{
+		TermID:         r.ID,
+		Slug:           old.Slug,
+		VocabularyName: old.VocabularyName,
+	}
+	err = db.Create(&redirect).Error
+	if err != nil {
+		return errors.Wrap(err, "TermModel.setSlug error on create slug redirect")
+	}
+
+	return nil
+}
+
+// Set slugs for new terms created together, checking for repeated slugs inside the list
+func termSetManySlugs(db *gorm.DB, records []TermModel) error {
+	reserved := map[string]bool{}
+
+	for i := range records {
+		base := records[i].Slug
+		if base == "" {
+			base = records[i].Text
+		}
+
+		base = termSlugMake(base)
+
+		newSlug, err := termUniqueSlug(db, base, records[i].VocabularyName, records[i].ID, reserved)
+		if err != nil {
+			return err
+		}
+
+		records[i].Slug = newSlug
+		reserved[records[i].VocabularyName+"/"+newSlug] = true
+	}
+
+	return nil
+}
+
+// Make one slug base from the text. All-digit slugs get one prefix because numeric url params are old term ids
+func termSlugMake(text string) string {
+	base := slug.Make(text)
+	if base == "" {
+		return "term"
+	}
+
+	if termSlugIsNumeric(base) {
+		return "term-" + base
+	}
+
+	return base
+}
+
+func termSlugIsNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Find one slug not used by other term or by other term old slug in the vocabulary, adding -2, -3 ... if needed
+func termUniqueSlug(db *gorm.DB, base, vocabularyName string, termID uint64, reserved map[string]bool) (string, error) {
+	candidate := base
+
+	for i := 2; ; i++ {
+		if reserved[vocabularyName+"/"+candidate] {
+			candidate = base + "-" + strconv.Itoa(i)
+			continue
+		}
+
+		var count int64
+		err := db.Model(&TermModel{}).
+			Where("slug = ? AND vocabularyName = ? AND id != ?", candidate, vocabularyName, termID).
+			Count(&count).Error
+		if err != nil {
+			return "", errors.Wrap(err, "termUniqueSlug error on count terms")
+		}
+
+		if count == 0 {
+			err = db.Model(&TermSlugRedirectModel{}).
+				Where("slug = ? AND vocabularyName = ? AND termId != ?", candidate, vocabularyName, termID).
+				Count(&count).Error
+			if err != nil {
+				return "", errors.Wrap(err, "termUniqueSlug error on count old slugs")
+			}
+		}
+
+		if count == 0 {
+			return candidate, nil
+		}
+
+		candidate = base + "-" + strconv.Itoa(i)
+	}
+}
@@ -0,0 +1,80 @@
+package tags
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTermSlugMake(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Go Lang", "go-lang"},
+		{"Programação", "programacao"},
+		{"2", "term-2"},
+		{"2024", "term-2024"},
+		{"2024 news", "2024-news"},
+		{"!!!", "term"},
+		{"", "term"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, termSlugMake(tt.text), tt.text)
+	}
+}
+
+func TestTermSlugs(t *testing.T) {
+	assert := assert.New(t)
+	db := GetAppInstance().GetDB()
+
+	first := TermModel{Text: "news", VocabularyName: "slugtags"}
+	assert.Nil(first.Save())
+	assert.Equal("news", first.Slug)
+
+	second := TermModel{Text: "News!", VocabularyName: "slugtags"}
+	assert.Nil(second.Save())
+	assert.Equal("news-2", second.Slug)
+
+	numeric := TermModel{Text: "2", VocabularyName: "slugtags"}
+	assert.Nil(numeric.Save())
+	assert.Equal("term-2", numeric.Slug)
+
+	t.Run("Should keep the old slug to redirect after rename", func(t *testing.T) {
+		first.Text = "breaking news"
+		assert.Nil(first.Save())
+		assert.Equal("breaking-news", first.Slug)
+
+		found := TermModel{}
+		assert.Nil(TermFindOneByOldSlug("news", "slugtags", &found))
+		assert.Equal(first.ID, found.ID)
+
+		// one new term can not take the old slug
+		third := TermModel{Text: "news", VocabularyName: "slugtags"}
+		assert.Nil(third.Save())
+		assert.Equal("news-3", third.Slug)
+	})
+
+	t.Run("Should find numeric params by id before old slugs", func(t *testing.T) {
+		// one legacy all-digit slug equal to other term id
+		legacy := TermModel{Text: "legacy", VocabularyName: "slugtags"}
+		assert.Nil(legacy.Save())
+		assert.Nil(db.Model(&legacy).Update("slug", first.GetIDString()).Error)
+
+		assert.Nil(TermEnsureSlugs())
+
+		saved := TermModel{}
+		assert.Nil(TermFindOne(legacy.GetIDString(), &saved))
+		assert.Equal("legacy", saved.Slug)
+
+		found := TermModel{}
+		assert.Nil(termFindMoved(context.Background(), first.GetIDString(), "slugtags", &found))
+		assert.Equal(first.ID, found.ID)
+
+		found = TermModel{}
+		assert.Nil(termFindMoved(context.Background(), "news", "slugtags", &found))
+		assert.Equal(first.ID, found.ID)
+	})
+}
@@ -0,0 +1,352 @@
+package tags
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-catupiry/catu"
+	"github.com/go-catupiry/catu/helpers"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// TermTranslationModel - Term text and description in one locale, the term own text is in the default locale
+type TermTranslationModel struct {
+	ID          uint64    `gorm:"primaryKey;column:id" json:"id"`
+	TermID      uint64    `gorm:"uniqueIndex:term_translations_termId_locale_IDX;column:termId;type:int(11);not null" json:"termId"`
+	Locale      string    `gorm:"uniqueIndex:term_translations_termId_locale_IDX;column:locale;type:varchar(20);not null" json:"locale"`
+	Text        string    `gorm:"column:text;type:varchar(255);not null" json:"text"`
+	Description string    `gorm:"column:description;type:text" json:"description"`
+	CreatedAt   time.Time `gorm:"column:createdAt;type:datetime;not null" json:"createdAt"`
+	UpdatedAt   time.Time `gorm:"column:updatedAt;type:datetime;not null" json:"updatedAt"`
+}
+
+// TableName - Set db table name for term translation model
+func (r *TermTranslationModel) TableName() string {
+	return "term_translations"
+}
+
+// Get the default locale from the DEFAULT_LOCALE configuration, terms text and description are in this locale
+func GetDefaultLocale() string {
+	return NormalizeLocale(catu.GetConfiguration().GetF("DEFAULT_LOCALE", "en"))
+}
+
+// Get the locales list from the LOCALES configuration, like: en,pt-BR
+func GetAvailableLocales() []string {
+	locales := []string{GetDefaultLocale()}
+
+	for _, l := range strings.Split(catu.GetConfiguration().Get("LOCALES"), ",") {
+		l = NormalizeLocale(l)
+		if l != "" && !helpers.SliceContains(locales, l) {
+			locales = append(locales, l)
+		}
+	}
+
+	return locales
+}
+
+// Normalize one locale to the language-REGION format, ex: pt_br -> pt-BR
+func NormalizeLocale(locale string) string {
+	locale = strings.TrimSpace(strings.Replace(locale, "_", "-", -1))
+	if locale == "" {
+		return ""
+	}
+
+	parts := strings.SplitN(locale, "-", 2)
+	if len(parts) == 1 {
+		return strings.ToLower(parts[0])
+	}
+
+	return strings.ToLower(parts[0]) + "-" + strings.ToUpper(parts[1])
+}
+
+// Get the available locale for one locale, matching by language if the region is not available
+func MatchAvailableLocale(locale string) string {
+	locale = NormalizeLocale(locale)
+	if locale == "" {
+		return ""
+	}
+
+	available := GetAvailableLocales()
+	if helpers.SliceContains(available, locale) {
+		return locale
+	}
+
+	language := strings.SplitN(locale, "-", 2)[0]
+	for _, l := range available {
+		if strings.SplitN(l, "-", 2)[0] == language {
+			return l
+		}
+	}
+
+	return ""
+}
+
+// Get the request locale from the :locale route param, locale query param, ctx locale value,
+// the authenticated user language or the Accept-Language header, in that order
+func GetRequestLocale(c echo.Context) string {
+	if l := MatchAvailableLocale(c.Param("locale")); l != "" {
+		return l
+	}
+
+	if l := MatchAvailableLocale(c.QueryParam("locale")); l != "" {
+		return l
+	}
+
+	if ctx, ok := c.(*catu.RequestContext); ok {
+		if l := MatchAvailableLocale(ctx.GetString("locale")); l != "" {
+			return l
+		}
+
+		if ctx.IsAuthenticated && ctx.AuthenticatedUser != nil {
+			if l := MatchAvailableLocale(ctx.AuthenticatedUser.GetLanguage()); l != "" {
+				return l
+			}
+		}
+	}
+
+	for _, part := range strings.Split(c.Request().Header.Get("Accept-Language"), ",") {
+		tag := strings.SplitN(part, ";", 2)[0]
+		if l := MatchAvailableLocale(tag); l != "" {
+			return l
+		}
+	}
+
+	return GetDefaultLocale()
+}
+
+// Get the locales to search for one translation, ex: pt-BR -> [pt-BR, pt].
+// The default locale is the term own text so it is not included
+func LocaleFallbacks(locale string) []string {
+	locale = NormalizeLocale(locale)
+	if locale == "" || locale == GetDefaultLocale() {
+		return []string{}
+	}
+
+	fallbacks := []string{locale}
+
+	language := strings.SplitN(locale, "-", 2)[0]
+	if language != locale && language != GetDefaultLocale() {
+		fallbacks = append(fallbacks, language)
+	}
+
+	return fallbacks
+}
+
+// Load term translations in r.Translations
+func (r *TermModel) LoadTranslations() error {
+	return r.LoadTranslationsContext(context.Background())
+}
+
+// LoadTranslationsContext - LoadTranslations using ctx in the database queries
+func (r *TermModel) LoadTranslationsContext(ctx context.Context) error {
+	records := []TermModel{*r}
+	err := TermLoadManyTranslationsContext(ctx, records)
+	if err != nil {
+		return err
+	}
+
+	r.Translations = records[0].Translations
+	return nil
+}
+
+// Load translations for a term list with only one query
+func TermLoadManyTranslations(records []TermModel) error {
+	return TermLoadManyTranslationsContext(context.Background(), records)
+}
+
+// TermLoadManyTranslationsContext - TermLoadManyTranslations using ctx in the database queries
+func TermLoadManyTranslationsContext(ctx context.Context, records []TermModel) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	ids := []uint64{}
+	for i := range records {
+		ids = append(ids, records[i].ID)
+	}
+
+	translations := []TermTranslationModel{}
+	err := db.Where("termId IN ?", ids).
+		Order("locale ASC").
+		Find(&translations).Error
+	if err != nil {
+		return errors.Wrap(err, "TermLoadManyTranslations error on find translations")
+	}
+
+	for i := range records {
+		records[i].Translations = []TermTranslationModel{}
+		for j := range translations {
+			if translations[j].TermID == records[i].ID {
+				records[i].Translations = append(records[i].Translations, translations[j])
+			}
+		}
+	}
+
+	return nil
+}
+
+// Replace the term translations with the r.Translations list, translations without text are removed
+func (r *TermModel) SaveTranslations() error {
+	return r.SaveTranslationsContext(context.Background())
+}
+
+// SaveTranslationsContext - SaveTranslations using ctx in the database queries
+func (r *TermModel) SaveTranslationsContext(ctx context.Context) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		return r.saveTranslations(tx)
+	})
+}
+
+// Replace the term translations in the tx transaction, used by SaveTranslations and Save
+func (r *TermModel) saveTranslations(tx *gorm.DB) error {
+	translations := []TermTranslationModel{}
+	locales := []string{}
+	for i := range r.Translations {
+		t := r.Translations[i]
+		t.Locale = NormalizeLocale(t.Locale)
+		t.Text = strings.TrimSpace(t.Text)
+
+		if t.Locale == "" || t.Text == "" || helpers.SliceContains(locales, t.Locale) {
+			continue
+		}
+
+		t.ID = 0
+		t.TermID = r.ID
+		translations = append(translations, t)
+		locales = append(locales, t.Locale)
+	}
+
+	saved := []TermTranslationModel{}
+	err := tx.Where("termId = ?", r.ID).Find(&saved).Error
+	if err != nil {
+		return errors.Wrap(err, "TermModel.SaveTranslations error on find saved translations")
+	}
+
+	for i := range saved {
+		if !helpers.SliceContains(locales, saved[i].Locale) {
+			err = tx.Delete(&saved[i]).Error
+			if err != nil {
+				return errors.Wrap(err, "TermModel.SaveTranslations error on delete translation")
+			}
+		}
+	}
+
+	for i := range translations {
+		for j := range saved {
+			if saved[j].Locale == translations[i].Locale {
+				translations[i].ID = saved[j].ID
+				translations[i].CreatedAt = saved[j].CreatedAt
+				break
+			}
+		}
+
+		err = tx.Save(&translations[i]).Error
+		if err != nil {
+			return errors.Wrap(err, "TermModel.SaveTranslations error on save translation")
+		}
+	}
+
+	r.Translations = translations
+
+	return nil
+}
+
+// Get the loaded translation for the locale with locale fallbacks, returns nil if the term text should be used
+func (r *TermModel) GetTranslation(locale string) *TermTranslationModel {
+	for _, l := range LocaleFallbacks(locale) {
+		for i := range r.Translations {
+			if r.Translations[i].Locale == l {
+				return &r.Translations[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// Get the term text in the locale, translations should be loaded
+func (r *TermModel) GetLocalizedText(locale string) string {
+	t := r.GetTranslation(locale)
+	if t == nil {
+		return r.Text
+	}
+
+	return t.Text
+}
+
+// Get the term description in the locale, translations should be loaded.
+// Empty translated descriptions fallback to the term description
+func (r *TermModel) GetLocalizedDescription(locale string) string {
+	t := r.GetTranslation(locale)
+	if t == nil || t.Description == "" {
+		return r.Description
+	}
+
+	return t.Description
+}
+
+// Get the term path with the locale prefix, ex: /pt-BR/vocabulary/Tags/term/golang
+func (r *TermModel) GetLocalizedPath(locale string) string {
+	locale = NormalizeLocale(locale)
+	path := r.GetPath()
+
+	if path == "" || locale == "" || locale == GetDefaultLocale() {
+		return path
+	}
+
+	return "/" + locale + path
+}
+
+// Subquery with the term ids with translations in the locale fallbacks, returns nil for the default locale
+func termTranslationsTermIDs(db *gorm.DB, locale string) *gorm.DB {
+	locales := LocaleFallbacks(locale)
+	if len(locales) == 0 {
+		return nil
+	}
+
+	return db.Model(&TermTranslationModel{}).
+		Select("termId").
+		Where("locale IN ?", locales)
+}
+
+// Set the locale used in the term LinkPermanent, the default locale is stored as empty
+func (r *TermModel) SetLocale(locale string) {
+	locale = NormalizeLocale(locale)
+	if locale == GetDefaultLocale() {
+		locale = ""
+	}
+
+	r.Locale = locale
+}
+
+// Load translations and localized paths for a term list
+func TermLoadManyLocalized(records []TermModel, locale string) error {
+	return TermLoadManyLocalizedContext(context.Background(), records, locale)
+}
+
+// TermLoadManyLocalizedContext - TermLoadManyLocalized using ctx in the database queries
+func TermLoadManyLocalizedContext(ctx context.Context, records []TermModel, locale string) error {
+	err := TermLoadManyTranslationsContext(ctx, records)
+	if err != nil {
+		return err
+	}
+
+	for i := range records {
+		records[i].SetLocale(locale)
+		records[i].LoadTeaserData()
+	}
+
+	return nil
+}
+
+// Check if the :locale route param, when used, is one available locale
+func isValidLocaleParam(c echo.Context) bool {
+	return c.Param("locale") == "" || MatchAvailableLocale(c.Param("locale")) != ""
+}
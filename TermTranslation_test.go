@@ -0,0 +1,148 @@
+package tags
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeLocale(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"", ""},
+		{"  ", ""},
+		{"en", "en"},
+		{"EN", "en"},
+		{"pt_br", "pt-BR"},
+		{"pt-br", "pt-BR"},
+		{" PT-br ", "pt-BR"},
+		{"zh-hant-tw", "zh-HANT-TW"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NormalizeLocale(tt.locale), tt.locale)
+	}
+}
+
+func TestLocaleFallbacks(t *testing.T) {
+	GetAppInstance()
+	t.Setenv("DEFAULT_LOCALE", "en")
+	t.Setenv("LOCALES", "pt-BR,es,en-GB")
+
+	tests := []struct {
+		locale string
+		want   []string
+	}{
+		{"", []string{}},
+		{"en", []string{}},
+		{"pt-br", []string{"pt-BR", "pt"}},
+		{"es", []string{"es"}},
+		// the default language is the term own text
+		{"en-GB", []string{"en-GB"}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, LocaleFallbacks(tt.locale), tt.locale)
+	}
+}
+
+func TestMatchAvailableLocale(t *testing.T) {
+	GetAppInstance()
+	t.Setenv("DEFAULT_LOCALE", "en")
+	t.Setenv("LOCALES", "pt-BR,es")
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"", ""},
+		{"en", "en"},
+		{"pt_BR", "pt-BR"},
+		{"pt", "pt-BR"},
+		{"pt-PT", "pt-BR"},
+		{"es-AR", "es"},
+		{"fr", ""},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, MatchAvailableLocale(tt.locale), tt.locale)
+	}
+}
+
+func TestLocaleRoutes(t *testing.T) {
+	assert := assert.New(t)
+	app := GetAppInstance()
+	t.Setenv("DEFAULT_LOCALE", "en")
+	t.Setenv("LOCALES", "pt-BR")
+
+	p := NewPlugin(&PluginCfgs{})
+	p.VocabularyController = NewVocabularyController(&VocabularyControllerCfg{App: app})
+	p.TermController = NewTermController(&TermControllerCfg{App: app})
+	assert.Nil(p.BindRoutes(app))
+
+	e := app.GetRouter()
+
+	tests := []struct {
+		path       string
+		route      string
+		locale     string
+		vocabulary string
+		slug       string
+	}{
+		{"/vocabulary/Tags", "/vocabulary/:vocabulary", "en", "Tags", ""},
+		{"/vocabulary/Tags/term/go", "/vocabulary/:vocabulary/term/:slug", "en", "Tags", "go"},
+		{"/pt-br/vocabulary/Tags", "/:locale/vocabulary/:vocabulary", "pt-BR", "Tags", ""},
+		{"/pt-BR/vocabulary/Tags/term/go", "/:locale/vocabulary/:vocabulary/term/:slug", "pt-BR", "Tags", "go"},
+		// unknown locales use the default locale
+		{"/fr/vocabulary/Tags/term/go", "/:locale/vocabulary/:vocabulary/term/:slug", "en", "Tags", "go"},
+	}
+
+	for _, tt := range tests {
+		c := e.NewContext(httptest.NewRequest("GET", tt.path, nil), httptest.NewRecorder())
+		e.Router().Find("GET", tt.path, c)
+
+		assert.Equal(tt.route, c.Path(), tt.path)
+		assert.Equal(tt.locale, GetRequestLocale(c), tt.path)
+		assert.Equal(tt.vocabulary, c.Param("vocabulary"), tt.path)
+		assert.Equal(tt.slug, c.Param("slug"), tt.path)
+	}
+}
+
+func TestTermSaveTranslations(t *testing.T) {
+	assert := assert.New(t)
+	GetAppInstance()
+
+	term := TermModel{
+		Text:           "house",
+		VocabularyName: "translationtags",
+		Translations: []TermTranslationModel{
+			{Locale: "pt_br", Text: " casa "},
+			{Locale: "pt-BR", Text: "repeated"},
+			{Locale: "es", Text: ""},
+		},
+	}
+	assert.Nil(term.Save())
+	assert.Equal(1, len(term.Translations))
+
+	loaded := TermModel{}
+	assert.Nil(TermFindOne(term.GetIDString(), &loaded))
+	assert.Nil(loaded.LoadTranslations())
+	assert.Equal("casa", loaded.GetLocalizedText("pt-BR"))
+	assert.Equal("house", loaded.GetLocalizedText("en"))
+
+	// one failed save keeps the saved translations
+	other := TermModel{Text: "home", VocabularyName: "translationtags", Aliases: []string{"dwelling"}}
+	assert.Nil(other.Save())
+
+	loaded.Aliases = []string{"dwelling"}
+	loaded.Translations = []TermTranslationModel{{Locale: "pt-BR", Text: "lar"}}
+	assert.ErrorIs(loaded.Save(), ErrTermAliasConflict)
+
+	saved := TermModel{}
+	assert.Nil(TermFindOne(term.GetIDString(), &saved))
+	assert.Nil(saved.LoadTranslations())
+	assert.Equal("casa", saved.GetLocalizedText("pt-BR"))
+}
@@ -0,0 +1,240 @@
+package tags
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/go-catupiry/catu"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var (
+	// Returned when a term parent would make the term its own ancestor
+	ErrTermParentCycle = errors.New("term parent would create a cycle")
+	// Returned when the term parent dont exists or is from other vocabulary
+	ErrTermParentInvalid = errors.New("term parent not found in the term vocabulary")
+)
+
+// TermTreeNode - One term with its children, used to render vocabulary trees
+type TermTreeNode struct {
+	TermModel
+	Children []*TermTreeNode `json:"children"`
+}
+
+// Find direct children of one term
+func TermFindChildren(id string, records *[]TermModel) error {
+	return TermFindChildrenContext(context.Background(), id, records)
+}
+
+// TermFindChildrenContext - TermFindChildren using ctx in the database queries
+func TermFindChildrenContext(ctx context.Context, id string, records *[]TermModel) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	return db.Where("parentId = ?", id).
+		Order("text ASC").
+		Find(records).Error
+}
+
+// Find all ancestors of one term, ordered from the root to the direct parent
+func TermFindAncestors(record *TermModel, records *[]TermModel) error {
+	return TermFindAncestorsContext(context.Background(), record, records)
+}
+
+// TermFindAncestorsContext - TermFindAncestors using ctx in the database queries
+func TermFindAncestorsContext(ctx context.Context, record *TermModel, records *[]TermModel) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	ancestors := []TermModel{}
+	visited := map[uint64]bool{record.ID: true}
+	parentID := record.ParentID
+
+	for parentID != nil && !visited[*parentID] {
+		parent := TermModel{}
+		err := db.Where("id = ?", *parentID).Limit(1).Find(&parent).Error
+		if err != nil {
+			return errors.Wrap(err, "TermFindAncestors error on find parent")
+		}
+
+		if parent.ID == 0 {
+			break
+		}
+
+		visited[parent.ID] = true
+		ancestors = append([]TermModel{parent}, ancestors...)
+		parentID = parent.ParentID
+	}
+
+	*records = ancestors
+
+	return nil
+}
+
+// Find the full subtree below one term, loaded level by level
+func TermFindDescendants(id uint64, records *[]TermModel) error {
+	return TermFindDescendantsContext(context.Background(), id, records)
+}
+
+// TermFindDescendantsContext - TermFindDescendants using ctx in the database queries
+func TermFindDescendantsContext(ctx context.Context, id uint64, records *[]TermModel) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	descendants := []TermModel{}
+	visited := map[uint64]bool{id: true}
+	levelIDs := []uint64{id}
+
+	for len(levelIDs) > 0 {
+		level := []TermModel{}
+		err := db.Where("parentId IN ?", levelIDs).
+			Order("text ASC").
+			Find(&level).Error
+		if err != nil {
+			return errors.Wrap(err, "TermFindDescendants error on find level")
+		}
+
+		levelIDs = []uint64{}
+		for i := range level {
+			if visited[level[i].ID] {
+				continue
+			}
+
+			visited[level[i].ID] = true
+			descendants = append(descendants, level[i])
+			levelIDs = append(levelIDs, level[i].ID)
+		}
+	}
+
+	*records = descendants
+
+	return nil
+}
+
+// Find the term id with all its descendant ids, used to query by one term subtree
+func TermFindSubtreeIDs(id string) ([]uint64, error) {
+	return TermFindSubtreeIDsContext(context.Background(), id)
+}
+
+// TermFindSubtreeIDsContext - TermFindSubtreeIDs using ctx in the database queries
+func TermFindSubtreeIDsContext(ctx context.Context, id string) ([]uint64, error) {
+	idn, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "TermFindSubtreeIDs invalid term id")
+	}
+
+	descendants := []TermModel{}
+	err = TermFindDescendantsContext(ctx, idn, &descendants)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []uint64{idn}
+	for i := range descendants {
+		ids = append(ids, descendants[i].ID)
+	}
+
+	return ids, nil
+}
+
+// Find all vocabulary terms and build the tree, terms without a valid parent are returned as roots
+func TermFindTree(vocabularyName string) ([]*TermTreeNode, error) {
+	return TermFindTreeContext(context.Background(), vocabularyName)
+}
+
+// TermFindTreeContext - TermFindTree using ctx in the database queries
+func TermFindTreeContext(ctx context.Context, vocabularyName string) ([]*TermTreeNode, error) {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	records := []TermModel{}
+	err := db.Where("vocabularyName = ?", vocabularyName).
+		Order("text ASC").
+		Find(&records).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "TermFindTree error on find terms")
+	}
+
+	err = TermLoadManyAliasesContext(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildTermTree(records), nil
+}
+
+// Build one term tree from a flat term list
+func BuildTermTree(records []TermModel) []*TermTreeNode {
+	nodes := make(map[uint64]*TermTreeNode, len(records))
+	for i := range records {
+		records[i].LoadTeaserData()
+		nodes[records[i].ID] = &TermTreeNode{TermModel: records[i], Children: []*TermTreeNode{}}
+	}
+
+	roots := []*TermTreeNode{}
+	for i := range records {
+		node := nodes[records[i].ID]
+		if records[i].ParentID != nil {
+			parent := nodes[*records[i].ParentID]
+			if parent != nil && parent != node && !termTreeNodeHasDescendant(node, parent) {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+
+		roots = append(roots, node)
+	}
+
+	return roots
+}
+
+func termTreeNodeHasDescendant(node, target *TermTreeNode) bool {
+	for _, child := range node.Children {
+		if child == target || termTreeNodeHasDescendant(child, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check if the parentID is a valid parent for the term, the parent should exist in the same vocabulary
+// and the term can not be one of the parent ancestors
+func TermValidateParent(record *TermModel, parentID uint64) error {
+	return TermValidateParentContext(context.Background(), record, parentID)
+}
+
+// TermValidateParentContext - TermValidateParent using ctx in the database queries
+func TermValidateParentContext(ctx context.Context, record *TermModel, parentID uint64) error {
+	if record.ID != 0 && record.ID == parentID {
+		return ErrTermParentCycle
+	}
+
+	parent := TermModel{}
+	err := TermFindOneContext(ctx, strconv.FormatUint(parentID, 10), &parent)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTermParentInvalid
+		}
+		return errors.Wrap(err, "TermValidateParent error on find parent")
+	}
+
+	if parent.ID == 0 || parent.VocabularyName != record.VocabularyName {
+		return ErrTermParentInvalid
+	}
+
+	if record.ID == 0 {
+		return nil
+	}
+
+	ancestors := []TermModel{}
+	err = TermFindAncestorsContext(ctx, &parent, &ancestors)
+	if err != nil {
+		return err
+	}
+
+	for i := range ancestors {
+		if ancestors[i].ID == record.ID {
+			return ErrTermParentCycle
+		}
+	}
+
+	return nil
+}
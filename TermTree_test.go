@@ -0,0 +1,180 @@
+package tags
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-catupiry/catu"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// root -> child -> grandchild, and one other root
+func saveTermTreeStub(assert *assert.Assertions, vocabularyName string) (root, child, grandchild, other TermModel) {
+	root = TermModel{Text: "root", VocabularyName: vocabularyName}
+	assert.Nil(root.Save())
+	child = TermModel{Text: "child", VocabularyName: vocabularyName, ParentID: &root.ID}
+	assert.Nil(child.Save())
+	grandchild = TermModel{Text: "grandchild", VocabularyName: vocabularyName, ParentID: &child.ID}
+	assert.Nil(grandchild.Save())
+	other = TermModel{Text: "other", VocabularyName: vocabularyName}
+	assert.Nil(other.Save())
+	return
+}
+
+func newTestRequestContext(target string) *catu.RequestContext {
+	req := httptest.NewRequest("GET", target, nil)
+	ec := echo.New().NewContext(req, httptest.NewRecorder())
+	return catu.NewRequestContext(&catu.RequestContextOpts{EchoContext: ec})
+}
+
+func TestTermFindTree(t *testing.T) {
+	assert := assert.New(t)
+	GetAppInstance()
+
+	root, child, grandchild, other := saveTermTreeStub(assert, "treecategory")
+
+	tree, err := TermFindTree("treecategory")
+	assert.Nil(err)
+	assert.Equal(2, len(tree))
+	assert.Equal(other.ID, tree[0].ID)
+	assert.Equal(root.ID, tree[1].ID)
+	assert.Equal(1, len(tree[1].Children))
+	assert.Equal(child.ID, tree[1].Children[0].ID)
+	assert.Equal(1, len(tree[1].Children[0].Children))
+	assert.Equal(grandchild.ID, tree[1].Children[0].Children[0].ID)
+
+	// cycles saved in the database are returned as roots
+	a := TermModel{ID: 1, Text: "a"}
+	b := TermModel{ID: 2, Text: "b"}
+	a.ParentID = &b.ID
+	b.ParentID = &a.ID
+	roots := BuildTermTree([]TermModel{a, b})
+	assert.Equal(1, len(roots))
+	assert.Equal(1, len(roots[0].Children))
+}
+
+func TestTermAncestorsAndDescendants(t *testing.T) {
+	assert := assert.New(t)
+	GetAppInstance()
+
+	root, child, grandchild, _ := saveTermTreeStub(assert, "treeancestors")
+
+	ancestors := []TermModel{}
+	assert.Nil(TermFindAncestors(&grandchild, &ancestors))
+	assert.Equal(2, len(ancestors))
+	assert.Equal(root.ID, ancestors[0].ID)
+	assert.Equal(child.ID, ancestors[1].ID)
+
+	ancestors = []TermModel{}
+	assert.Nil(TermFindAncestors(&root, &ancestors))
+	assert.Empty(ancestors)
+
+	descendants := []TermModel{}
+	assert.Nil(TermFindDescendants(root.ID, &descendants))
+	assert.Equal(2, len(descendants))
+
+	ids, err := TermFindSubtreeIDs(child.GetIDString())
+	assert.Nil(err)
+	assert.ElementsMatch([]uint64{child.ID, grandchild.ID}, ids)
+
+	_, err = TermFindSubtreeIDs("invalid")
+	assert.NotNil(err)
+}
+
+func TestTermValidateParent(t *testing.T) {
+	assert := assert.New(t)
+	GetAppInstance()
+
+	root, child, grandchild, other := saveTermTreeStub(assert, "treevalidate")
+	foreign := TermModel{Text: "foreign", VocabularyName: "treevalidateother"}
+	assert.Nil(foreign.Save())
+
+	assert.ErrorIs(TermValidateParent(&root, root.ID), ErrTermParentCycle)
+	assert.ErrorIs(TermValidateParent(&root, grandchild.ID), ErrTermParentCycle)
+	assert.ErrorIs(TermValidateParent(&child, grandchild.ID), ErrTermParentCycle)
+	assert.ErrorIs(TermValidateParent(&root, foreign.ID), ErrTermParentInvalid)
+	assert.ErrorIs(TermValidateParent(&root, 999999), ErrTermParentInvalid)
+	assert.Nil(TermValidateParent(&grandchild, other.ID))
+	assert.Nil(TermValidateParent(&TermModel{VocabularyName: "treevalidate"}, grandchild.ID))
+}
+
+func TestModelstermQueryAndCountReq_Subtree(t *testing.T) {
+	assert := assert.New(t)
+	db := GetAppInstance().GetDB()
+
+	root, child, grandchild, other := saveTermTreeStub(assert, "treequery")
+
+	// record 1 has the root and grandchild, only listed once in the root subtree
+	for _, a := range []struct {
+		modelID uint64
+		termID  uint64
+	}{{1, root.ID}, {1, grandchild.ID}, {2, child.ID}, {3, other.ID}} {
+		assoc, _ := NewModelsterms("treequery", "treepost", "category", a.modelID, a.termID)
+		assert.Nil(db.Create(&assoc).Error)
+	}
+
+	query := func(termID string) ([]ModelstermsModel, int64) {
+		var count int64
+		records := []ModelstermsModel{}
+		err := ModelstermQueryAndCountReq(&ModelstermQueryOpts{
+			Records: &records,
+			Count:   &count,
+			Limit:   10,
+			C:       newTestRequestContext("/"),
+			TermID:  termID,
+		})
+		assert.Nil(err)
+		return records, count
+	}
+
+	records, count := query(root.GetIDString())
+	assert.Equal(2, len(records))
+	assert.Equal(int64(2), count)
+
+	records, count = query(child.GetIDString())
+	assert.Equal(2, len(records))
+	assert.Equal(int64(2), count)
+
+	records, count = query(other.GetIDString())
+	assert.Equal(1, len(records))
+	assert.Equal(int64(1), count)
+	assert.Equal(uint64(3), records[0].ModelID)
+}
+
+func TestTermDelete(t *testing.T) {
+	assert := assert.New(t)
+	db := GetAppInstance().GetDB()
+
+	root, child, grandchild, _ := saveTermTreeStub(assert, "deletecategory")
+
+	t.Run("Should keep the children if the delete fails", func(t *testing.T) {
+		err := db.Callback().Delete().Before("gorm:delete").Register("test:fail_term_delete", func(db *gorm.DB) {
+			if db.Statement.Table == "terms" {
+				db.AddError(errors.New("delete failed"))
+			}
+		})
+		assert.Nil(err)
+
+		assert.NotNil(child.Delete())
+		assert.Nil(db.Callback().Delete().Remove("test:fail_term_delete"))
+
+		saved := TermModel{}
+		assert.Nil(TermFindOne(grandchild.GetIDString(), &saved))
+		assert.Equal(child.ID, *saved.ParentID)
+	})
+
+	t.Run("Should move the children to the term parent", func(t *testing.T) {
+		assert.Nil(child.Delete())
+
+		saved := TermModel{}
+		assert.Nil(TermFindOne(grandchild.GetIDString(), &saved))
+		assert.Equal(root.ID, *saved.ParentID)
+
+		deleted := TermModel{}
+		assert.Nil(db.Where("id = ?", child.ID).Limit(1).Find(&deleted).Error)
+		assert.Equal(uint64(0), deleted.ID)
+	})
+}
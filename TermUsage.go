@@ -0,0 +1,63 @@
+package tags
+
+import (
+	"context"
+
+	"github.com/go-catupiry/catu"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Update the usage count of the terms with the number of associations of each term
+func termUpdateUsage(db *gorm.DB, termIds []uint64) error {
+	if len(termIds) == 0 {
+		return nil
+	}
+
+	return termSetUsage(db.Model(&TermModel{}).Where("id IN ?", termIds))
+}
+
+func termSetUsage(query *gorm.DB) error {
+	count := query.Session(&gorm.Session{NewDB: true}).
+		Model(&ModelstermsModel{}).
+		Select("COUNT(*)").
+		Where("modelsterms.termId = terms.id")
+
+	return query.UpdateColumn("usageCount", count).Error
+}
+
+// Get the term ids of the associations found with the query
+func modelstermsTermIDs(query *gorm.DB) ([]uint64, error) {
+	termIds := []uint64{}
+	err := query.Model(&ModelstermsModel{}).
+		Distinct("termId").
+		Where("termId IS NOT NULL").
+		Pluck("termId", &termIds).Error
+
+	return termIds, err
+}
+
+// Recount the usage of all vocabulary terms, use it to repair usage counts changed outside this plugin.
+// All terms are recounted if the vocabularyName is empty
+func TermRecountUsage(vocabularyName string) error {
+	return TermRecountUsageContext(context.Background(), vocabularyName)
+}
+
+// TermRecountUsageContext - TermRecountUsage using ctx in the database queries
+func TermRecountUsageContext(ctx context.Context, vocabularyName string) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	query := db.Model(&TermModel{})
+	if vocabularyName != "" {
+		query = query.Where("vocabularyName = ?", vocabularyName)
+	} else {
+		query = query.Where("1 = 1")
+	}
+
+	err := termSetUsage(query)
+	if err != nil {
+		return errors.Wrap(err, "TermRecountUsage error on update usage")
+	}
+
+	return nil
+}
@@ -1,9 +1,14 @@
 package tags
 
 import (
+	"bytes"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-catupiry/catu"
+	"github.com/go-catupiry/metatags"
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -43,7 +48,7 @@ func (ctl *VocabularyController) Query(c echo.Context) error {
 
 	var count int64
 	var records []VocabularyModel
-	err = VocabularyQueryAndCountReq(&VocabularyQueryOpts{
+	err = VocabularyQueryAndCountReqContext(c.Request().Context(), &VocabularyQueryOpts{
 		Records: &records,
 		Count:   &count,
 		Limit:   RequestContext.GetLimit(),
@@ -110,7 +115,7 @@ func (ctl *VocabularyController) Create(c echo.Context) error {
 		"body": body,
 	}).Info("VocabularyController.Create params")
 
-	err = record.Save()
+	err = record.SaveContext(c.Request().Context())
 	if err != nil {
 		return err
 	}
@@ -132,7 +137,7 @@ func (ctl *VocabularyController) Count(c echo.Context) error {
 	RequestContext := c.(*catu.RequestContext)
 
 	var count int64
-	err = VocabularyCountReq(&VocabularyQueryOpts{
+	err = VocabularyCountReqContext(c.Request().Context(), &VocabularyQueryOpts{
 		Count:  &count,
 		Limit:  RequestContext.GetLimit(),
 		Offset: RequestContext.GetOffset(),
@@ -161,7 +166,7 @@ func (ctl *VocabularyController) FindOne(c echo.Context) error {
 	}).Debug("VocabularyController.FindOne id from params")
 
 	record := VocabularyModel{}
-	err := VocabularyFindOne(id, &record)
+	err := VocabularyFindOneContext(c.Request().Context(), id, &record)
 	if err != nil {
 		return err
 	}
@@ -174,6 +179,13 @@ func (ctl *VocabularyController) FindOne(c echo.Context) error {
 		return echo.NotFoundHandler(c)
 	}
 
+	// the JSON and SKOS responses share the URL
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+
+	if contentType := skosContentTypeFromReq(c); contentType != "" {
+		return renderVocabularySKOS(c, record.Name, contentType)
+	}
+
 	record.LoadData()
 
 	resp := VocabularyFindOneJSONResponse{
@@ -201,7 +213,7 @@ func (ctl *VocabularyController) Update(c echo.Context) error {
 	}
 
 	record := VocabularyModel{}
-	err = VocabularyFindOne(id, &record)
+	err = VocabularyFindOneContext(c.Request().Context(), id, &record)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"id":    id,
@@ -226,7 +238,7 @@ func (ctl *VocabularyController) Update(c echo.Context) error {
 		return c.NoContent(http.StatusNotFound)
 	}
 
-	err = record.Save()
+	err = record.SaveContext(c.Request().Context())
 	if err != nil {
 		return err
 	}
@@ -254,12 +266,12 @@ func (ctl *VocabularyController) Delete(c echo.Context) error {
 	}
 
 	record := VocabularyModel{}
-	err = VocabularyFindOne(id, &record)
+	err = VocabularyFindOneContext(c.Request().Context(), id, &record)
 	if err != nil {
 		return err
 	}
 
-	err = record.Delete()
+	err = record.DeleteContext(c.Request().Context())
 	if err != nil {
 		return err
 	}
@@ -268,7 +280,146 @@ func (ctl *VocabularyController) Delete(c echo.Context) error {
 }
 
 func (ctl *VocabularyController) FindAllPageHandler(c echo.Context) error {
-	panic("TODO!")
+	ctx := c.(*catu.RequestContext)
+
+	switch ctx.GetResponseContentType() {
+	case "application/json":
+		return ctl.Query(c)
+	}
+
+	var count int64
+	var records []VocabularyModel
+	err := VocabularyQueryAndCountReqContext(c.Request().Context(), &VocabularyQueryOpts{
+		Records: &records,
+		Count:   &count,
+		Limit:   ctx.GetLimit(),
+		Offset:  ctx.GetOffset(),
+		C:       c,
+		IsHTML:  true,
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Debug("VocabularyController.FindAllPageHandler Error on find vocabularies")
+	}
+
+	for i := range records {
+		records[i].LoadTeaserData()
+	}
+
+	ctx.Set("hasRecords", len(records) > 0)
+	ctx.Set("q", c.QueryParam("q"))
+	ctx.Set("RequestPath", ctx.Request().URL.String())
+
+	ctx.Title = "Vocabularies"
+	ctx.BodyClass = append(ctx.BodyClass, "body-vocabulary-findAll")
+
+	mt := c.Get("metatags").(*metatags.HTMLMetaTags)
+	mt.Title = ctx.Title
+
+	ctx.Pager.Count = count
+
+	return c.Render(http.StatusOK, "taxonomy/vocabulary/findAll", &catu.TemplateCTX{
+		Ctx:     ctx,
+		Records: &records,
+	})
+}
+
+// Export - Download the :vocabulary vocabulary and its terms. Query params: format (csv or json, default json)
+func (ctl *VocabularyController) Export(c echo.Context) error {
+	ctx := c.(*catu.RequestContext)
+
+	can := ctx.Can("export_vocabulary")
+	if !can {
+		return echo.NewHTTPError(http.StatusForbidden, "Forbidden")
+	}
+
+	vocabularyName := c.Param("vocabulary")
+	format := vocabularyImportFormatFromReq(c)
+
+	contentType := echo.MIMEApplicationJSONCharsetUTF8
+	if format == "csv" {
+		contentType = "text/csv; charset=UTF-8"
+	}
+
+	var buf bytes.Buffer
+	err := VocabularyExportToContext(c.Request().Context(), &buf, vocabularyName, format)
+	if err != nil {
+		if errors.Is(err, ErrVocabularyImportFormat) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return errors.Wrap(err, "VocabularyController.Export error on export")
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", vocabularyName+"."+format))
+
+	return c.Blob(http.StatusOK, contentType, buf.Bytes())
+}
+
+type VocabularyImportJSONResponse struct {
+	Result *VocabularyImportResult `json:"import"`
+}
+
+// Import - Import terms in the :vocabulary vocabulary from the request body or from the multipart file field.
+// Query params: format (csv or json, default from the file extension or content type) and dryRun
+func (ctl *VocabularyController) Import(c echo.Context) error {
+	ctx := c.(*catu.RequestContext)
+
+	can := ctx.Can("import_vocabulary")
+	if !can {
+		return echo.NewHTTPError(http.StatusForbidden, "Forbidden")
+	}
+
+	body := c.Request().Body
+	format := vocabularyImportFormatFromReq(c)
+
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return errors.Wrap(err, "VocabularyController.Import error on open file")
+		}
+		defer f.Close()
+
+		body = f
+		if c.QueryParam("format") == "" && strings.HasSuffix(strings.ToLower(file.Filename), ".csv") {
+			format = "csv"
+		}
+	}
+
+	dryRun, _ := strconv.ParseBool(c.QueryParam("dryRun"))
+
+	logrus.WithFields(logrus.Fields{
+		"vocabulary": c.Param("vocabulary"),
+		"format":     format,
+		"dryRun":     dryRun,
+	}).Info("VocabularyController.Import params")
+
+	result, err := VocabularyImportContext(c.Request().Context(), body, &VocabularyImportOpts{
+		VocabularyName: c.Param("vocabulary"),
+		Format:         format,
+		DryRun:         dryRun,
+	})
+	if err != nil {
+		if errors.Is(err, ErrVocabularyImportFormat) || errors.Is(err, ErrVocabularyImportInvalid) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return errors.Wrap(err, "VocabularyController.Import error on import")
+	}
+
+	return c.JSON(http.StatusOK, &VocabularyImportJSONResponse{Result: result})
+}
+
+// Get the import and export format from the format query param or the request content type
+func vocabularyImportFormatFromReq(c echo.Context) string {
+	if format := c.QueryParam("format"); format != "" {
+		return strings.ToLower(format)
+	}
+
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), "text/csv") {
+		return "csv"
+	}
+
+	return "json"
 }
 
 type VocabularyControllerCfg struct {
@@ -0,0 +1,557 @@
+package tags
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/go-catupiry/catu"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrVocabularyImportFormat - Import or export format is not csv or json
+	ErrVocabularyImportFormat = errors.New("vocabulary import format must be csv or json")
+	// ErrVocabularyImportInvalid - The import file can not be parsed
+	ErrVocabularyImportInvalid = errors.New("invalid vocabulary import file")
+
+	// returned inside the import transaction to rollback dry runs
+	errVocabularyImportDryRun = errors.New("vocabulary import dry run")
+)
+
+// Columns of the CSV import and export files, text is required in imports
+var VocabularyCSVColumns = []string{"text", "description", "parent", "slug"}
+
+// VocabularyExportData - One vocabulary with its terms, the import and export JSON format
+type VocabularyExportData struct {
+	Vocabulary VocabularyExportInfo   `json:"vocabulary"`
+	Terms      []VocabularyExportTerm `json:"terms"`
+}
+
+type VocabularyExportInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// VocabularyExportTerm - One term, the parent is the parent term text. Imports only change the description and
+// parent of existing terms if set, ex: present in the CSV header or JSON term, an empty parent moves the term to the root
+type VocabularyExportTerm struct {
+	Text        string  `json:"text"`
+	Description *string `json:"description"`
+	Parent      *string `json:"parent"`
+	Slug        string  `json:"slug"`
+
+	// source row used in import errors
+	row int
+}
+
+type VocabularyImportOpts struct {
+	// Vocabulary to import, defaults to the vocabulary name of the JSON file. Created if not exists
+	VocabularyName string
+	// csv or json
+	Format string
+	// Validate and report the changes without saving them
+	DryRun bool
+}
+
+// VocabularyImportRowError - One row that was not imported. Row is the CSV line or the JSON term position,
+// both starting at 1
+type VocabularyImportRowError struct {
+	Row     int    `json:"row"`
+	Text    string `json:"text"`
+	Message string `json:"message"`
+}
+
+type VocabularyImportResult struct {
+	VocabularyName string                     `json:"vocabularyName"`
+	DryRun         bool                       `json:"dryRun"`
+	Created        int                        `json:"created"`
+	Updated        int                        `json:"updated"`
+	Unchanged      int                        `json:"unchanged"`
+	Errors         []VocabularyImportRowError `json:"errors"`
+}
+
+// Get the parent text, empty if not set
+func (t *VocabularyExportTerm) parentText() string {
+	if t.Parent == nil {
+		return ""
+	}
+	return *t.Parent
+}
+
+func (r *VocabularyImportResult) addError(row int, text, message string) {
+	r.Errors = append(r.Errors, VocabularyImportRowError{Row: row, Text: text, Message: message})
+}
+
+// Export one vocabulary and its terms
+func VocabularyExport(vocabularyName string) (*VocabularyExportData, error) {
+	return VocabularyExportContext(context.Background(), vocabularyName)
+}
+
+// VocabularyExportContext - VocabularyExport using ctx in the database queries
+func VocabularyExportContext(ctx context.Context, vocabularyName string) (*VocabularyExportData, error) {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	vocabulary := VocabularyModel{}
+	err := VocabularyFindOneByNameContext(ctx, vocabularyName, &vocabulary)
+	if err != nil {
+		return nil, errors.Wrap(err, "VocabularyExport error on find vocabulary")
+	}
+
+	records := []TermModel{}
+	err = db.Where("vocabularyName = ?", vocabularyName).
+		Order("id ASC").
+		Find(&records).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "VocabularyExport error on find terms")
+	}
+
+	texts := map[uint64]string{}
+	for i := range records {
+		texts[records[i].ID] = records[i].Text
+	}
+
+	data := VocabularyExportData{
+		Vocabulary: VocabularyExportInfo{
+			Name:        vocabularyName,
+			Description: vocabulary.Description,
+		},
+		Terms: []VocabularyExportTerm{},
+	}
+
+	for i := range records {
+		description := records[i].Description
+		parent := ""
+		if records[i].ParentID != nil {
+			parent = texts[*records[i].ParentID]
+		}
+
+		data.Terms = append(data.Terms, VocabularyExportTerm{
+			Text:        records[i].Text,
+			Description: &description,
+			Parent:      &parent,
+			Slug:        records[i].Slug,
+		})
+	}
+
+	return &data, nil
+}
+
+// Write one vocabulary export in the csv or json format
+func VocabularyExportTo(w io.Writer, vocabularyName, format string) error {
+	return VocabularyExportToContext(context.Background(), w, vocabularyName, format)
+}
+
+// VocabularyExportToContext - VocabularyExportTo using ctx in the database queries
+func VocabularyExportToContext(ctx context.Context, w io.Writer, vocabularyName, format string) error {
+	if format != "csv" && format != "json" {
+		return ErrVocabularyImportFormat
+	}
+
+	data, err := VocabularyExportContext(ctx, vocabularyName)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(data)
+	}
+
+	cw := csv.NewWriter(w)
+	err = cw.Write(VocabularyCSVColumns)
+	if err != nil {
+		return errors.Wrap(err, "VocabularyExportTo error on write csv header")
+	}
+
+	for _, t := range data.Terms {
+		description := ""
+		if t.Description != nil {
+			description = *t.Description
+		}
+
+		err = cw.Write([]string{t.Text, description, t.parentText(), t.Slug})
+		if err != nil {
+			return errors.Wrap(err, "VocabularyExportTo error on write csv row")
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// Parse one csv or json import file. CSV files need one header row with the VocabularyCSVColumns names,
+// rows that can not be parsed are returned as row errors
+func VocabularyImportParse(r io.Reader, format string) (*VocabularyExportData, []VocabularyImportRowError, error) {
+	switch format {
+	case "json":
+		data := VocabularyExportData{}
+		err := json.NewDecoder(r).Decode(&data)
+		if err != nil {
+			return nil, nil, errors.Wrap(ErrVocabularyImportInvalid, err.Error())
+		}
+
+		for i := range data.Terms {
+			data.Terms[i].row = i + 1
+		}
+
+		return &data, nil, nil
+	case "csv":
+		return vocabularyImportParseCSV(r)
+	default:
+		return nil, nil, ErrVocabularyImportFormat
+	}
+}
+
+func vocabularyImportParseCSV(r io.Reader) (*VocabularyExportData, []VocabularyImportRowError, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, errors.Wrap(ErrVocabularyImportInvalid, "csv header: "+err.Error())
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	if _, ok := columns["text"]; !ok {
+		return nil, nil, errors.Wrap(ErrVocabularyImportInvalid, "csv header without the text column")
+	}
+
+	data := VocabularyExportData{}
+	var rowErrors []VocabularyImportRowError
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, VocabularyImportRowError{Row: parseErr.StartLine, Message: parseErr.Err.Error()})
+				continue
+			}
+			return nil, nil, errors.Wrap(err, "VocabularyImportParse error on read csv")
+		}
+
+		line, _ := cr.FieldPos(0)
+
+		value := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		// nil if the column is not in the file, so the import does not change it
+		optional := func(column string) *string {
+			if _, ok := columns[column]; !ok {
+				return nil
+			}
+			v := value(column)
+			return &v
+		}
+
+		data.Terms = append(data.Terms, VocabularyExportTerm{
+			Text:        value("text"),
+			Description: optional("description"),
+			Parent:      optional("parent"),
+			Slug:        value("slug"),
+			row:         line,
+		})
+	}
+
+	return &data, rowErrors, nil
+}
+
+// Import one vocabulary file, see VocabularyImportData
+func VocabularyImport(r io.Reader, opts *VocabularyImportOpts) (*VocabularyImportResult, error) {
+	return VocabularyImportContext(context.Background(), r, opts)
+}
+
+// VocabularyImportContext - VocabularyImport using ctx in the database queries
+func VocabularyImportContext(ctx context.Context, r io.Reader, opts *VocabularyImportOpts) (*VocabularyImportResult, error) {
+	data, rowErrors, err := VocabularyImportParse(r, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := VocabularyImportDataContext(ctx, data, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Errors = append(rowErrors, result.Errors...)
+
+	return result, nil
+}
+
+// Import the vocabulary and upsert its terms by text. Rows with errors are skipped and reported in the result,
+// the other rows are imported. With opts.DryRun all changes are rolled back
+func VocabularyImportData(data *VocabularyExportData, opts *VocabularyImportOpts) (*VocabularyImportResult, error) {
+	return VocabularyImportDataContext(context.Background(), data, opts)
+}
+
+// VocabularyImportDataContext - VocabularyImportData using ctx in the database queries
+func VocabularyImportDataContext(ctx context.Context, data *VocabularyExportData, opts *VocabularyImportOpts) (*VocabularyImportResult, error) {
+	vocabularyName := opts.VocabularyName
+	if vocabularyName == "" {
+		vocabularyName = strings.TrimSpace(data.Vocabulary.Name)
+	}
+	if vocabularyName == "" {
+		return nil, errors.Wrap(ErrVocabularyImportInvalid, "vocabulary name is required")
+	}
+
+	result := VocabularyImportResult{
+		VocabularyName: vocabularyName,
+		DryRun:         opts.DryRun,
+		Errors:         []VocabularyImportRowError{},
+	}
+
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := vocabularyImportVocabulary(tx, vocabularyName, data.Vocabulary.Description)
+		if err != nil {
+			return err
+		}
+
+		err = vocabularyImportTerms(tx, vocabularyName, data.Terms, &result)
+		if err != nil {
+			return err
+		}
+
+		if opts.DryRun {
+			return errVocabularyImportDryRun
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errVocabularyImportDryRun) {
+		return nil, errors.Wrap(err, "VocabularyImportData error on import")
+	}
+
+	return &result, nil
+}
+
+// Create the vocabulary if not exists or update its description if set
+func vocabularyImportVocabulary(tx *gorm.DB, name, description string) error {
+	vocabulary := VocabularyModel{}
+	err := tx.Where("name = ?", name).Limit(1).Find(&vocabulary).Error
+	if err != nil {
+		return errors.Wrap(err, "error on find vocabulary")
+	}
+
+	if vocabulary.ID == 0 {
+		vocabulary.Name = name
+		vocabulary.Description = description
+		return tx.Create(&vocabulary).Error
+	}
+
+	if description == "" || description == vocabulary.Description {
+		return nil
+	}
+
+	return tx.Model(&vocabulary).Update("description", description).Error
+}
+
+// Upsert the terms with the TermModel save rules. Terms are saved after their parents, so parents may come after
+// their children in the file, and rows with one parent not found or in one cycle are not saved
+func vocabularyImportTerms(tx *gorm.DB, vocabularyName string, terms []VocabularyExportTerm, result *VocabularyImportResult) error {
+	existing := []TermModel{}
+	err := tx.Where("vocabularyName = ?", vocabularyName).Find(&existing).Error
+	if err != nil {
+		return errors.Wrap(err, "error on find terms")
+	}
+
+	byText := map[string]*TermModel{}
+	parents := map[uint64]uint64{}
+	for i := range existing {
+		byText[existing[i].Text] = &existing[i]
+		if existing[i].ParentID != nil {
+			parents[existing[i].ID] = *existing[i].ParentID
+		}
+	}
+
+	onlyLowercase := false
+	if p := taxonomyGetPlugin(); p != nil {
+		onlyLowercase = p.IsVocabularyOnlyLowercase(vocabularyName)
+	}
+
+	imported := map[string]bool{}
+	pending := []VocabularyExportTerm{}
+
+	for _, t := range terms {
+		t.Text = strings.TrimSpace(t.Text)
+		if onlyLowercase {
+			t.Text = strings.ToLower(t.Text)
+		}
+
+		if t.Parent != nil {
+			parent := strings.TrimSpace(*t.Parent)
+			if onlyLowercase {
+				parent = strings.ToLower(parent)
+			}
+			t.Parent = &parent
+		}
+
+		switch {
+		case t.Text == "":
+			result.addError(t.row, t.Text, "text is required")
+			continue
+		case len(t.Text) > 255:
+			result.addError(t.row, t.Text, "text is longer than 255 characters")
+			continue
+		case imported[t.Text]:
+			result.addError(t.row, t.Text, "term is repeated in the file")
+			continue
+		}
+		imported[t.Text] = true
+
+		pending = append(pending, t)
+	}
+
+	// save the rows with one saved parent until no row can be saved
+	for saved := true; saved; {
+		saved = false
+		waiting := []VocabularyExportTerm{}
+
+		for _, t := range pending {
+			if t.parentText() != "" && byText[t.parentText()] == nil {
+				waiting = append(waiting, t)
+				continue
+			}
+
+			saved = true
+			vocabularyImportTerm(tx, vocabularyName, t, byText, parents, result)
+		}
+
+		pending = waiting
+	}
+
+	waitingParents := map[string]string{}
+	for _, t := range pending {
+		waitingParents[t.Text] = t.parentText()
+	}
+
+	for _, t := range pending {
+		if vocabularyImportParentsLoop(waitingParents, t.Text) {
+			result.addError(t.row, t.Text, ErrTermParentCycle.Error())
+		} else {
+			result.addError(t.row, t.Text, fmt.Sprintf("parent term %q not found in the vocabulary", t.parentText()))
+		}
+	}
+
+	return nil
+}
+
+// Check if the parents of the text, in the not saved rows, have one cycle
+func vocabularyImportParentsLoop(parents map[string]string, text string) bool {
+	visited := map[string]bool{text: true}
+	for current, ok := parents[text]; ok; current, ok = parents[current] {
+		if visited[current] {
+			return true
+		}
+		visited[current] = true
+	}
+
+	return false
+}
+
+// Create or update one term row in one savepoint, so row errors do not rollback the other rows
+func vocabularyImportTerm(tx *gorm.DB, vocabularyName string, t VocabularyExportTerm, byText map[string]*TermModel, parents map[uint64]uint64, result *VocabularyImportResult) {
+	current := byText[t.Text]
+	isNew := current == nil
+
+	// the description and parent are kept if not set
+	var parentID uint64
+	if t.parentText() != "" {
+		parentID = byText[t.parentText()].ID
+	} else if t.Parent == nil && !isNew {
+		parentID = parents[current.ID]
+	}
+
+	description := ""
+	if t.Description != nil {
+		description = *t.Description
+	} else if !isNew {
+		description = current.Description
+	}
+
+	if !isNew {
+		if parentID != 0 && termImportCreatesCycle(parents, current.ID, parentID) {
+			result.addError(t.row, t.Text, ErrTermParentCycle.Error())
+			return
+		}
+
+		if current.Description == description && (t.Slug == "" || t.Slug == current.Slug) && parentID == parents[current.ID] {
+			result.Unchanged++
+			return
+		}
+	}
+
+	// change one copy, the byText term is kept on errors
+	record := TermModel{Text: t.Text, VocabularyName: vocabularyName}
+	if !isNew {
+		record = *current
+	}
+
+	record.Description = description
+	if t.Slug != "" {
+		record.Slug = t.Slug
+	}
+
+	record.ParentID = nil
+	if parentID != 0 {
+		record.ParentID = &parentID
+	}
+
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		return record.save(tx)
+	})
+	if err != nil {
+		result.addError(t.row, t.Text, err.Error())
+		return
+	}
+
+	byText[t.Text] = &record
+
+	if parentID == 0 {
+		delete(parents, record.ID)
+	} else {
+		parents[record.ID] = parentID
+	}
+
+	if isNew {
+		result.Created++
+	} else {
+		result.Updated++
+	}
+}
+
+// Check if setting parentID as the id parent makes id one of its own ancestors
+func termImportCreatesCycle(parents map[uint64]uint64, id, parentID uint64) bool {
+	visited := map[uint64]bool{}
+	for current := parentID; current != 0 && !visited[current]; current = parents[current] {
+		if current == id {
+			return true
+		}
+		visited[current] = true
+	}
+
+	return false
+}
@@ -0,0 +1,182 @@
+package tags
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVocabularyImport(t *testing.T) {
+	assert := assert.New(t)
+	app := GetAppInstance()
+
+	// the sqlite test vocabularies table has no auto increment id
+	err := app.GetDB().Create(&VocabularyModel{ID: 9001, Name: "importcategory"}).Error
+	assert.Nil(err)
+
+	file := `text,description,parent
+Health,Health news,
+Food,Food and drinks,Health
+,Without text,
+Science,,Unknown
+Food,Repeated,
+Tech,"Tech, gadgets"
+"broken
+`
+
+	result, err := VocabularyImport(strings.NewReader(file), &VocabularyImportOpts{
+		VocabularyName: "importcategory",
+		Format:         "csv",
+		DryRun:         true,
+	})
+	if !assert.Nil(err) {
+		return
+	}
+	assert.True(result.DryRun)
+	// the Science row has one parent error and is not imported
+	assert.Equal(3, result.Created)
+	assert.Equal(4, len(result.Errors))
+
+	rows := []int{}
+	for _, e := range result.Errors {
+		rows = append(rows, e.Row)
+	}
+	assert.ElementsMatch([]int{4, 5, 6, 8}, rows)
+
+	record := TermModel{}
+	assert.Nil(TermFindOneByText("Health", "importcategory", &record))
+	assert.Equal(uint64(0), record.ID)
+
+	result, err = VocabularyImport(strings.NewReader(file), &VocabularyImportOpts{
+		VocabularyName: "importcategory",
+		Format:         "csv",
+	})
+	assert.Nil(err)
+	assert.Equal(3, result.Created)
+
+	science := TermModel{}
+	assert.Nil(TermFindOneByText("Science", "importcategory", &science))
+	assert.Equal(uint64(0), science.ID)
+
+	food := TermModel{}
+	assert.Nil(TermFindOneByText("Food", "importcategory", &food))
+	assert.Equal("Food and drinks", food.Description)
+	assert.NotNil(food.ParentID)
+
+	var buf bytes.Buffer
+	assert.Nil(VocabularyExportTo(&buf, "importcategory", "json"))
+
+	data, _, err := VocabularyImportParse(&buf, "json")
+	assert.Nil(err)
+	assert.Equal(3, len(data.Terms))
+
+	// upsert by text and parent cycles
+	description, parent := "Updated", "Food"
+	data.Terms[0].Description = &description
+	data.Terms[0].Parent = &parent
+	result, err = VocabularyImportData(data, &VocabularyImportOpts{})
+	assert.Nil(err)
+	assert.Equal("importcategory", result.VocabularyName)
+	assert.Equal(0, result.Created)
+	assert.Equal(0, result.Updated)
+	assert.Equal(2, result.Unchanged)
+	assert.Equal(1, len(result.Errors))
+	assert.Equal(ErrTermParentCycle.Error(), result.Errors[0].Message)
+
+	health := TermModel{}
+	assert.Nil(TermFindOneByText(data.Terms[0].Text, "importcategory", &health))
+	assert.NotEqual("Updated", health.Description)
+	assert.Nil(health.ParentID)
+
+	_, err = VocabularyImport(strings.NewReader("name\nx"), &VocabularyImportOpts{VocabularyName: "importcategory", Format: "csv"})
+	assert.ErrorIs(err, ErrVocabularyImportInvalid)
+
+	t.Run("Should keep the description and parent if not in the file", func(t *testing.T) {
+		files := []struct {
+			format string
+			file   string
+		}{
+			{"csv", "text\nFood\nHealth\n"},
+			{"json", `{"terms": [{"text": "Food"}, {"text": "Health", "slug": "health"}]}`},
+		}
+
+		for _, f := range files {
+			result, err := VocabularyImport(strings.NewReader(f.file), &VocabularyImportOpts{
+				VocabularyName: "importcategory",
+				Format:         f.format,
+			})
+			assert.Nil(err, f.format)
+			assert.Equal(0, result.Updated, f.format)
+			assert.Equal(2, result.Unchanged, f.format)
+
+			saved := TermModel{}
+			assert.Nil(TermFindOneByText("Food", "importcategory", &saved))
+			assert.Equal("Food and drinks", saved.Description, f.format)
+			assert.NotNil(saved.ParentID, f.format)
+		}
+
+		// one empty parent moves the term to the root
+		result, err := VocabularyImport(strings.NewReader("text,parent\nFood,\n"), &VocabularyImportOpts{
+			VocabularyName: "importcategory",
+			Format:         "csv",
+		})
+		assert.Nil(err)
+		assert.Equal(1, result.Updated)
+
+		saved := TermModel{}
+		assert.Nil(TermFindOneByText("Food", "importcategory", &saved))
+		assert.Equal("Food and drinks", saved.Description)
+		assert.Nil(saved.ParentID)
+	})
+}
+
+func TestVocabularyImport_SaveRules(t *testing.T) {
+	assert := assert.New(t)
+	app := GetAppInstance()
+
+	p := NewPlugin(&PluginCfgs{})
+	assert.Nil(p.RegisterField(NewTagFieldConfiguration("importtags", "importpost", "tags")))
+	app.SetPlugin("taxonomy", p)
+	defer delete(app.GetPlugins(), "taxonomy")
+
+	assert.Nil(app.GetDB().Create(&VocabularyModel{ID: 9101, Name: "importtags"}).Error)
+
+	golang := TermModel{Text: "go", VocabularyName: "importtags", Aliases: []string{"golang"}}
+	assert.Nil(golang.Save())
+
+	// parents after children, one text used as alias, one parents cycle and one child of one not imported row
+	file := `text,description,parent
+Web,,Programming
+Programming,,
+Golang,,Programming
+A,,B
+B,,A
+C,,Golang
+`
+
+	result, err := VocabularyImport(strings.NewReader(file), &VocabularyImportOpts{
+		VocabularyName: "importtags",
+		Format:         "csv",
+	})
+	assert.Nil(err)
+	assert.Equal(2, result.Created)
+
+	messages := map[int]string{}
+	for _, e := range result.Errors {
+		messages[e.Row] = e.Message
+	}
+	assert.Equal(map[int]string{
+		4: ErrTermAliasConflict.Error(),
+		5: ErrTermParentCycle.Error(),
+		6: ErrTermParentCycle.Error(),
+		7: `parent term "golang" not found in the vocabulary`,
+	}, messages)
+
+	web := TermModel{}
+	assert.Nil(TermFindOneByText("web", "importtags", &web))
+	programming := TermModel{}
+	assert.Nil(TermFindOneByText("programming", "importtags", &programming))
+	assert.Equal(programming.ID, *web.ParentID)
+}
@@ -1,6 +1,7 @@
 package tags
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -34,6 +35,8 @@ type VocabularyModel struct {
 	CreatedAt   time.Time `gorm:"column:createdAt;type:datetime;not null" json:"createdAt"`
 	UpdatedAt   time.Time `gorm:"column:updatedAt;type:datetime;not null" json:"updatedAt"`
 	CreatorID   *uint64   `gorm:"index:creatorId;column:creatorId;type:int(11)" json:"creatorId,omitempty"`
+	// Exclude the vocabulary and its term pages from sitemaps
+	NoIndex bool `gorm:"column:noIndex;not null;default:false" json:"noIndex"`
 	// Users       User      `gorm:"joinForeignKey:creatorId;foreignKey:id" json:"usersList"` // We.js users table
 
 	LinkPermanent string `gorm:"-" json:"linkPermanent"`
@@ -54,8 +57,13 @@ func (m *VocabularyModel) ToJSON() string {
 }
 
 func (m *VocabularyModel) Save() error {
+	return m.SaveContext(context.Background())
+}
+
+// SaveContext - Save using ctx in the database queries
+func (m *VocabularyModel) SaveContext(ctx context.Context) error {
 	var err error
-	db := catu.GetDefaultDatabaseConnection()
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
 
 	if m.ID == 0 {
 		// create ....
@@ -86,7 +94,9 @@ func (r *VocabularyModel) LoadData() error {
 func (r *VocabularyModel) GetPath() string {
 	path := ""
 
-	if r.ID != 0 {
+	if r.Name != "" {
+		path += "/vocabulary/" + r.Name
+	} else if r.ID != 0 {
 		path += "/vocabulary/" + r.GetIDString()
 	}
 
@@ -110,40 +120,61 @@ type VocabularyQueryOpts struct {
 
 // FindOne - Find one b3news.Content record
 func VocabularyFindOne(id string, record *VocabularyModel) error {
-	db := catu.GetDefaultDatabaseConnection()
+	return VocabularyFindOneContext(context.Background(), id, record)
+}
+
+// VocabularyFindOneContext - VocabularyFindOne using ctx in the database queries
+func VocabularyFindOneContext(ctx context.Context, id string, record *VocabularyModel) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
 
 	return db.First(&record, id).Error
 }
 
+// Find one vocabulary by name, record.ID will be 0 if not found
+func VocabularyFindOneByName(name string, record *VocabularyModel) error {
+	return VocabularyFindOneByNameContext(context.Background(), name, record)
+}
+
+// VocabularyFindOneByNameContext - VocabularyFindOneByName using ctx in the database queries
+func VocabularyFindOneByNameContext(ctx context.Context, name string, record *VocabularyModel) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	return db.Where("name = ?", name).
+		Limit(1).
+		Find(record).Error
+}
+
 func (r *VocabularyModel) Delete() error {
-	db := catu.GetDefaultDatabaseConnection()
+	return r.DeleteContext(context.Background())
+}
+
+// DeleteContext - Delete using ctx in the database queries
+func (r *VocabularyModel) DeleteContext(ctx context.Context) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
 	return db.Unscoped().Delete(&r).Error
 }
 
 func VocabularyQueryAndCountReq(opts *VocabularyQueryOpts) error {
-	db := catu.GetDefaultDatabaseConnection()
+	return VocabularyQueryAndCountReqContext(context.Background(), opts)
+}
 
-	c := opts.C
+// VocabularyQueryAndCountReqContext - VocabularyQueryAndCountReq using ctx in the database queries
+func VocabularyQueryAndCountReqContext(ctx context.Context, opts *VocabularyQueryOpts) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
 
-	q := c.QueryParam("q")
+	c := opts.C
 
 	query := db
 
-	ctx := c.(*catu.RequestContext)
+	rctx := c.(*catu.RequestContext)
 
-	queryI, err := ctx.Query.SetDatabaseQueryForModel(query, &VocabularyModel{})
+	queryI, err := rctx.Query.SetDatabaseQueryForModel(query, &VocabularyModel{})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": fmt.Sprintf("%+v\n", err),
 		}).Error("QueryAndCountReq error")
 	}
-	query = queryI.(*gorm.DB)
-
-	if q != "" {
-		query = query.Where(
-			db.Where("name LIKE ?", "%"+q+"%").Or(db.Where("description LIKE ?", "%"+q+"%")),
-		)
-	}
+	query = vocabularyQueryReqFilters(db, queryI.(*gorm.DB), c)
 
 	orderColumn, orderIsDesc, orderValid := helpers.ParseUrlQueryOrder(c.QueryParam("order"), c.QueryParam("sort"), c.QueryParam("sortDirection"))
 
@@ -166,37 +197,46 @@ func VocabularyQueryAndCountReq(opts *VocabularyQueryOpts) error {
 		return r.Error
 	}
 
-	return VocabularyCountReq(opts)
+	return VocabularyCountReqContext(ctx, opts)
 }
 
 func VocabularyCountReq(opts *VocabularyQueryOpts) error {
-	db := catu.GetDefaultDatabaseConnection()
+	return VocabularyCountReqContext(context.Background(), opts)
+}
 
-	c := opts.C
+// VocabularyCountReqContext - VocabularyCountReq using ctx in the database queries
+func VocabularyCountReqContext(ctx context.Context, opts *VocabularyQueryOpts) error {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
 
-	q := c.QueryParam("q")
+	c := opts.C
 
-	ctx := c.(*catu.RequestContext)
+	rctx := c.(*catu.RequestContext)
 
 	// Count ...
 	queryCount := db
 
-	if q != "" {
-		queryCount = queryCount.Or(
-			db.Where("name LIKE ?", "%"+q+"%"),
-			db.Where("description LIKE ?", "%"+q+"%"),
-		)
-	}
-
-	queryICount, err := ctx.Query.SetDatabaseQueryForModel(queryCount, &VocabularyModel{})
+	queryICount, err := rctx.Query.SetDatabaseQueryForModel(queryCount, &VocabularyModel{})
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": fmt.Sprintf("%+v\n", err),
 		}).Error("vocabulary count error")
 	}
-	queryCount = queryICount.(*gorm.DB)
+	queryCount = vocabularyQueryReqFilters(db, queryICount.(*gorm.DB), c)
 
 	return queryCount.
 		Table("vocabularies").
 		Count(opts.Count).Error
 }
+
+// Apply the request q filter, shared by the vocabulary query and count
+func vocabularyQueryReqFilters(db, query *gorm.DB, c echo.Context) *gorm.DB {
+	q := c.QueryParam("q")
+
+	if q != "" {
+		query = query.Where(
+			db.Where("name LIKE ?", "%"+q+"%").Or(db.Where("description LIKE ?", "%"+q+"%")),
+		)
+	}
+
+	return query
+}
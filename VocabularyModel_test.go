@@ -0,0 +1,34 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVocabularyQueryAndCountReq(t *testing.T) {
+	assert := assert.New(t)
+	db := GetAppInstance().GetDB()
+
+	// the sqlite test vocabularies table has no auto increment id
+	for i, v := range []VocabularyModel{
+		{Name: "vocabularyquery tags", Description: "Content tags"},
+		{Name: "Category", Description: "vocabularyquery categories"},
+		{Name: "Other", Description: "Other vocabulary"},
+	} {
+		v.ID = uint64(9201 + i)
+		assert.Nil(db.Create(&v).Error)
+	}
+
+	var count int64
+	records := []VocabularyModel{}
+	err := VocabularyQueryAndCountReq(&VocabularyQueryOpts{
+		Records: &records,
+		Count:   &count,
+		Limit:   1,
+		C:       newTestRequestContext("/vocabulary?q=vocabularyquery"),
+	})
+	assert.Nil(err)
+	assert.Equal(1, len(records))
+	assert.Equal(int64(2), count)
+}
@@ -0,0 +1,307 @@
+package tags
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/go-catupiry/catu"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+const (
+	SKOSTurtleContentType = "text/turtle"
+	SKOSJSONLDContentType = "application/ld+json"
+)
+
+// SKOSContext - JSON-LD context of the SKOS exports
+var SKOSContext = map[string]interface{}{
+	"skos":          "http://www.w3.org/2004/02/skos/core#",
+	"dct":           "http://purl.org/dc/terms/",
+	"title":         "dct:title",
+	"description":   "dct:description",
+	"prefLabel":     map[string]string{"@id": "skos:prefLabel", "@container": "@language"},
+	"altLabel":      map[string]string{"@id": "skos:altLabel", "@container": "@language"},
+	"definition":    map[string]string{"@id": "skos:definition", "@container": "@language"},
+	"inScheme":      map[string]string{"@id": "skos:inScheme", "@type": "@id"},
+	"topConceptOf":  map[string]string{"@id": "skos:topConceptOf", "@type": "@id"},
+	"hasTopConcept": map[string]string{"@id": "skos:hasTopConcept", "@type": "@id"},
+	"broader":       map[string]string{"@id": "skos:broader", "@type": "@id"},
+	"narrower":      map[string]string{"@id": "skos:narrower", "@type": "@id"},
+	"related":       map[string]string{"@id": "skos:related", "@type": "@id"},
+}
+
+// SKOSConceptScheme - One vocabulary as a skos:ConceptScheme
+type SKOSConceptScheme struct {
+	ID            string   `json:"@id"`
+	Type          string   `json:"@type"`
+	Title         string   `json:"title"`
+	Description   string   `json:"description,omitempty"`
+	HasTopConcept []string `json:"hasTopConcept,omitempty"`
+}
+
+// SKOSConcept - One term as a skos:Concept, labels and definitions are indexed by locale
+type SKOSConcept struct {
+	ID           string              `json:"@id"`
+	Type         string              `json:"@type"`
+	PrefLabel    map[string]string   `json:"prefLabel"`
+	AltLabel     map[string][]string `json:"altLabel,omitempty"`
+	Definition   map[string]string   `json:"definition,omitempty"`
+	InScheme     string              `json:"inScheme"`
+	TopConceptOf string              `json:"topConceptOf,omitempty"`
+	Broader      []string            `json:"broader,omitempty"`
+	Narrower     []string            `json:"narrower,omitempty"`
+	// Out of scope of VocabularySKOS, it is never filled: the plugin stores no related assertions and the
+	// TermFindRelated co-occurrence is a statistic, not a skos:related link. Set it before writing the export
+	// to publish related concepts
+	Related []string `json:"related,omitempty"`
+}
+
+// SKOSExport - One vocabulary with its terms as SKOS, see VocabularySKOS
+type SKOSExport struct {
+	Scheme   SKOSConceptScheme
+	Concepts []SKOSConcept
+}
+
+// Build the SKOS export of one vocabulary. Term links are the concept URIs, translations are exported as
+// labels in their locales, aliases as altLabel and parents as broader / narrower
+func VocabularySKOS(vocabularyName string) (*SKOSExport, error) {
+	return VocabularySKOSContext(context.Background(), vocabularyName)
+}
+
+// VocabularySKOSContext - VocabularySKOS using ctx in the database queries
+func VocabularySKOSContext(ctx context.Context, vocabularyName string) (*SKOSExport, error) {
+	db := catu.GetDefaultDatabaseConnection().WithContext(ctx)
+
+	// vocabularies like Tags may be used without a saved vocabulary record
+	vocabulary := VocabularyModel{}
+	err := VocabularyFindOneByNameContext(ctx, vocabularyName, &vocabulary)
+	if err != nil {
+		return nil, errors.Wrap(err, "VocabularySKOS error on find vocabulary")
+	}
+	vocabulary.Name = vocabularyName
+	vocabulary.LoadPath()
+
+	records := []TermModel{}
+	err = db.Where("vocabularyName = ?", vocabularyName).
+		Order("id ASC").
+		Find(&records).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "VocabularySKOS error on find terms")
+	}
+
+	err = TermLoadManyAliasesContext(ctx, records)
+	if err != nil {
+		return nil, errors.Wrap(err, "VocabularySKOS error on load aliases")
+	}
+
+	err = TermLoadManyTranslationsContext(ctx, records)
+	if err != nil {
+		return nil, errors.Wrap(err, "VocabularySKOS error on load translations")
+	}
+
+	locale := GetDefaultLocale()
+
+	uris := map[uint64]string{}
+	for i := range records {
+		records[i].Locale = ""
+		records[i].LoadPath()
+		uris[records[i].ID] = records[i].LinkPermanent
+	}
+
+	export := SKOSExport{
+		Scheme: SKOSConceptScheme{
+			ID:          vocabulary.LinkPermanent,
+			Type:        "skos:ConceptScheme",
+			Title:       vocabularyName,
+			Description: vocabulary.Description,
+		},
+		Concepts: []SKOSConcept{},
+	}
+
+	narrower := map[uint64][]string{}
+	for i := range records {
+		if records[i].ParentID != nil && uris[*records[i].ParentID] != "" {
+			narrower[*records[i].ParentID] = append(narrower[*records[i].ParentID], records[i].LinkPermanent)
+		}
+	}
+
+	for i := range records {
+		r := &records[i]
+
+		concept := SKOSConcept{
+			ID:        r.LinkPermanent,
+			Type:      "skos:Concept",
+			PrefLabel: map[string]string{locale: r.Text},
+			InScheme:  export.Scheme.ID,
+			Narrower:  narrower[r.ID],
+		}
+
+		if r.Description != "" {
+			concept.Definition = map[string]string{locale: r.Description}
+		}
+
+		if len(r.Aliases) > 0 {
+			concept.AltLabel = map[string][]string{locale: r.Aliases}
+		}
+
+		for _, t := range r.Translations {
+			concept.PrefLabel[t.Locale] = t.Text
+			if t.Description != "" {
+				if concept.Definition == nil {
+					concept.Definition = map[string]string{}
+				}
+				concept.Definition[t.Locale] = t.Description
+			}
+		}
+
+		if r.ParentID != nil && uris[*r.ParentID] != "" {
+			concept.Broader = []string{uris[*r.ParentID]}
+		} else {
+			concept.TopConceptOf = export.Scheme.ID
+			export.Scheme.HasTopConcept = append(export.Scheme.HasTopConcept, r.LinkPermanent)
+		}
+
+		export.Concepts = append(export.Concepts, concept)
+	}
+
+	return &export, nil
+}
+
+// WriteJSONLD - Write the export as one JSON-LD document
+func (e *SKOSExport) WriteJSONLD(w io.Writer) error {
+	graph := []interface{}{e.Scheme}
+	for i := range e.Concepts {
+		graph = append(graph, e.Concepts[i])
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(map[string]interface{}{
+		"@context": SKOSContext,
+		"@graph":   graph,
+	})
+}
+
+// WriteTurtle - Write the export as one Turtle document
+func (e *SKOSExport) WriteTurtle(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString("@prefix skos: <http://www.w3.org/2004/02/skos/core#> .\n")
+	bw.WriteString("@prefix dct: <http://purl.org/dc/terms/> .\n\n")
+
+	bw.WriteString(turtleIRI(e.Scheme.ID) + " a skos:ConceptScheme")
+	writeTurtleProperty(bw, "dct:title", turtleLiteral(e.Scheme.Title, ""))
+	if e.Scheme.Description != "" {
+		writeTurtleProperty(bw, "dct:description", turtleLiteral(e.Scheme.Description, ""))
+	}
+	writeTurtleIRIs(bw, "skos:hasTopConcept", e.Scheme.HasTopConcept)
+	bw.WriteString(" .\n")
+
+	for i := range e.Concepts {
+		c := &e.Concepts[i]
+
+		bw.WriteString("\n" + turtleIRI(c.ID) + " a skos:Concept")
+		writeTurtleProperty(bw, "skos:inScheme", turtleIRI(c.InScheme))
+		if c.TopConceptOf != "" {
+			writeTurtleProperty(bw, "skos:topConceptOf", turtleIRI(c.TopConceptOf))
+		}
+
+		for _, locale := range sortedKeys(c.PrefLabel) {
+			writeTurtleProperty(bw, "skos:prefLabel", turtleLiteral(c.PrefLabel[locale], locale))
+		}
+		for _, locale := range sortedKeys(c.AltLabel) {
+			for _, label := range c.AltLabel[locale] {
+				writeTurtleProperty(bw, "skos:altLabel", turtleLiteral(label, locale))
+			}
+		}
+		for _, locale := range sortedKeys(c.Definition) {
+			writeTurtleProperty(bw, "skos:definition", turtleLiteral(c.Definition[locale], locale))
+		}
+
+		writeTurtleIRIs(bw, "skos:broader", c.Broader)
+		writeTurtleIRIs(bw, "skos:narrower", c.Narrower)
+		writeTurtleIRIs(bw, "skos:related", c.Related)
+		bw.WriteString(" .\n")
+	}
+
+	return bw.Flush()
+}
+
+func writeTurtleProperty(bw *bufio.Writer, predicate, object string) {
+	bw.WriteString(" ;\n    " + predicate + " " + object)
+}
+
+func writeTurtleIRIs(bw *bufio.Writer, predicate string, iris []string) {
+	for _, iri := range iris {
+		writeTurtleProperty(bw, predicate, turtleIRI(iri))
+	}
+}
+
+func turtleIRI(iri string) string {
+	return "<" + strings.NewReplacer(">", "%3E", "<", "%3C", " ", "%20", `"`, "%22", "\\", "%5C").Replace(iri) + ">"
+}
+
+func turtleLiteral(value, locale string) string {
+	literal := `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(value) + `"`
+	if locale != "" {
+		literal += "@" + locale
+	}
+	return literal
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Get the SKOS response content type from the format query param (ttl or jsonld) or the Accept header,
+// returns an empty string for other formats
+func skosContentTypeFromReq(c echo.Context) string {
+	switch c.QueryParam("format") {
+	case "ttl", "turtle":
+		return SKOSTurtleContentType
+	case "jsonld":
+		return SKOSJSONLDContentType
+	}
+
+	// the first supported type wins, browsers send text/html first
+	for _, part := range strings.Split(c.Request().Header.Get(echo.HeaderAccept), ",") {
+		switch strings.TrimSpace(strings.SplitN(part, ";", 2)[0]) {
+		case SKOSTurtleContentType:
+			return SKOSTurtleContentType
+		case SKOSJSONLDContentType:
+			return SKOSJSONLDContentType
+		case "text/html", "application/json":
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// Write the vocabulary SKOS export in the contentType format, the caller sets the Vary header
+func renderVocabularySKOS(c echo.Context, vocabularyName, contentType string) error {
+	export, err := VocabularySKOSContext(c.Request().Context(), vocabularyName)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, contentType+"; charset=UTF-8")
+	c.Response().WriteHeader(200)
+
+	if contentType == SKOSTurtleContentType {
+		return export.WriteTurtle(c.Response())
+	}
+
+	return export.WriteJSONLD(c.Response())
+}
@@ -0,0 +1,70 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVocabularySKOS(t *testing.T) {
+	assert := assert.New(t)
+	GetAppInstance()
+
+	parent := TermModel{Text: "Science", Description: "All \"sciences\"", VocabularyName: "skoscategory"}
+	assert.Nil(parent.Save())
+
+	child := TermModel{Text: "Physics", VocabularyName: "skoscategory", ParentID: &parent.ID, Aliases: []string{"Physical science"}}
+	assert.Nil(child.Save())
+
+	export, err := VocabularySKOS("skoscategory")
+	assert.Nil(err)
+	assert.Equal(2, len(export.Concepts))
+	assert.Equal([]string{parent.GetPath()}, export.Scheme.HasTopConcept)
+	assert.Equal([]string{export.Concepts[1].ID}, export.Concepts[0].Narrower)
+	assert.Equal([]string{export.Concepts[0].ID}, export.Concepts[1].Broader)
+	assert.Equal([]string{"Physical science"}, export.Concepts[1].AltLabel["en"])
+
+	var ttl bytes.Buffer
+	assert.Nil(export.WriteTurtle(&ttl))
+	assert.Contains(ttl.String(), "<"+child.GetPath()+"> a skos:Concept")
+	assert.Contains(ttl.String(), `skos:definition "All \"sciences\""@en`)
+	assert.Contains(ttl.String(), "skos:broader <"+parent.GetPath()+">")
+	assert.True(strings.HasSuffix(ttl.String(), " .\n"))
+
+	var jsonld bytes.Buffer
+	assert.Nil(export.WriteJSONLD(&jsonld))
+
+	doc := map[string]interface{}{}
+	assert.Nil(json.Unmarshal(jsonld.Bytes(), &doc))
+	assert.NotNil(doc["@context"])
+	assert.Equal(3, len(doc["@graph"].([]interface{})))
+}
+
+func TestVocabularyControllerFindOneVary(t *testing.T) {
+	assert := assert.New(t)
+	db := GetAppInstance().GetDB()
+
+	vocabulary := VocabularyModel{Name: "skosvary"}
+	vocabulary.ID = 9301
+	assert.Nil(db.Create(&vocabulary).Error)
+
+	ctl := VocabularyController{}
+
+	for _, accept := range []string{"application/json", SKOSTurtleContentType} {
+		req := httptest.NewRequest("GET", "/vocabulary/9301", nil)
+		req.Header.Set(echo.HeaderAccept, accept)
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("9301")
+
+		assert.Nil(ctl.FindOne(c))
+		assert.Equal(200, rec.Code)
+		assert.Equal(echo.HeaderAccept, rec.Header().Get(echo.HeaderVary), accept)
+	}
+}
@@ -1,12 +1,24 @@
 package tags
 
 import (
+	"context"
 	"strconv"
+	"strings"
 
 	"github.com/go-catupiry/catu"
 	"github.com/go-catupiry/catu/helpers"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// Returned when one term text dont exists and the field can not create new terms
+	ErrFieldCanNotCreateTerm = errors.New("term does not exist and the field cannot create it")
+	// Returned when more than one term is set in one field that is not multiple
+	ErrFieldOnlyOneTerm = errors.New("field accepts only one term")
+	// Returned when one empty term text is added
+	ErrFieldEmptyTermText = errors.New("term text is empty")
 )
 
 // Field configuration interface implements basic term fields logic
@@ -33,6 +45,50 @@ type FieldConfigurationInterface interface {
 	ClearField(modelId string) error
 }
 
+// Optional FieldConfigurationInterface methods for fields that only accept lowercase term texts,
+// see FieldIsOnlyLowercase
+type FieldLowercaseConfigurationInterface interface {
+	IsOnlyLowercase() bool
+	SetOnlyLowercase(v bool) error
+}
+
+// Optional FieldConfigurationInterface methods to run the field queries with one db connection, transaction
+// or context, see FieldWithDB and FieldWithContext. The FieldConfiguration methods changing the DB run in one
+// transaction nested in the WithDB transaction if set
+type FieldDBConfigurationInterface interface {
+	WithDB(db *gorm.DB) FieldConfigurationInterface
+	WithContext(ctx context.Context) FieldConfigurationInterface
+}
+
+// Check if the field only accepts lowercase term texts, false if the field dont implements FieldLowercaseConfigurationInterface
+func FieldIsOnlyLowercase(f FieldConfigurationInterface) bool {
+	if l, ok := f.(FieldLowercaseConfigurationInterface); ok {
+		return l.IsOnlyLowercase()
+	}
+
+	return false
+}
+
+// Get the field using the db connection or transaction, the field is returned as is if it dont implements
+// FieldDBConfigurationInterface
+func FieldWithDB(f FieldConfigurationInterface, db *gorm.DB) FieldConfigurationInterface {
+	if d, ok := f.(FieldDBConfigurationInterface); ok {
+		return d.WithDB(db)
+	}
+
+	return f
+}
+
+// Get the field using ctx in the db queries, the field is returned as is if it dont implements
+// FieldDBConfigurationInterface
+func FieldWithContext(f FieldConfigurationInterface, ctx context.Context) FieldConfigurationInterface {
+	if d, ok := f.(FieldDBConfigurationInterface); ok {
+		return d.WithContext(ctx)
+	}
+
+	return f
+}
+
 // Term field configuration to associate contents with terms
 type FieldConfiguration struct {
 	DB               *gorm.DB
@@ -56,6 +112,15 @@ func (f *FieldConfiguration) SetFormFieldMultiple(v bool) error {
 	return nil
 }
 
+func (f *FieldConfiguration) IsOnlyLowercase() bool {
+	return f.OnlyLowercase
+}
+
+func (f *FieldConfiguration) SetOnlyLowercase(v bool) error {
+	f.OnlyLowercase = v
+	return nil
+}
+
 func (f *FieldConfiguration) CanCreateTerm() bool {
 	return f.CanCreate
 }
@@ -92,6 +157,37 @@ func (f *FieldConfiguration) SetVocabularyName(name string) error {
 	return nil
 }
 
+// Get a field configuration copy using the db connection or transaction, ex:
+//
+//	err := db.Transaction(func(tx *gorm.DB) error {
+//		err := tx.Save(&article).Error
+//		if err != nil {
+//			return err
+//		}
+//		return tags.FieldWithDB(cfg, tx).Update(article.GetIDString(), article.Tags)
+//	})
+func (f *FieldConfiguration) WithDB(db *gorm.DB) FieldConfigurationInterface {
+	c := *f
+	c.DB = db
+	return &c
+}
+
+// Get a field configuration copy using ctx in the db queries, ex: cfg.WithContext(c.Request().Context()).Update(...)
+func (f *FieldConfiguration) WithContext(ctx context.Context) FieldConfigurationInterface {
+	c := *f
+	c.DB = f.DB.WithContext(ctx)
+	return &c
+}
+
+// Run fn in one transaction with a field configuration copy using it
+func (f *FieldConfiguration) transaction(fn func(ft *FieldConfiguration) error) error {
+	return f.DB.Transaction(func(tx *gorm.DB) error {
+		ft := *f
+		ft.DB = tx
+		return fn(&ft)
+	})
+}
+
 func (f *FieldConfiguration) FindOneTerm(modelId string, target *TermModel) error {
 	err := f.DB.
 		Joins(`INNER JOIN modelsterms AS A on
@@ -116,7 +212,8 @@ func (f *FieldConfiguration) FindManyTerm(modelId string, target *[]TermModel) e
 			A.modelName = ? AND
 			A.modelId = ? AND
 			A.termId = terms.id`, f.GetVocabularyName(), f.GetFieldName(), f.GetModelName(), modelId).
-		Order("'order' ASC").
+		Order(clause.OrderByColumn{Column: clause.Column{Table: "A", Name: "order"}}).
+		Order("A.id ASC").
 		// Where("modelName = ? AND modelId = ?", "modelName", "modelId").
 		Find(&target).Error
 	if err != nil {
@@ -139,31 +236,123 @@ func (f *FieldConfiguration) FindOneAssoc(modelId, termId string, target *Models
 }
 
 func (f *FieldConfiguration) Add(modelId, termText string) (*TermModel, *ModelstermsModel, error) {
+	var term *TermModel
+	var assoc *ModelstermsModel
+
+	err := f.transaction(func(ft *FieldConfiguration) error {
+		var err error
+		term, assoc, err = ft.add(modelId, termText)
+		return err
+	})
+
+	return term, assoc, err
+}
+
+func (f *FieldConfiguration) add(modelId, termText string) (*TermModel, *ModelstermsModel, error) {
+	texts := f.NormalizeTexts([]string{termText})
+	if len(texts) == 0 {
+		return nil, nil, ErrFieldEmptyTermText
+	}
+
 	newTerm := TermModel{}
-	err := TermFindOneByText(termText, f.GetVocabularyName(), &newTerm)
+	err := termFindOneByText(f.DB, texts[0], f.GetVocabularyName(), &newTerm)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "FieldConfiguration.AddByText error on find new term text")
 	}
 
+	if newTerm.ID == 0 {
+		if !f.CanCreateTerm() {
+			return nil, nil, ErrFieldCanNotCreateTerm
+		}
+
+		newTerm = TermModel{
+			Text:           texts[0],
+			VocabularyName: f.GetVocabularyName(),
+		}
+
+		err = newTerm.setSlug(f.DB)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "FieldConfiguration.AddByText error on set term slug")
+		}
+
+		err = f.DB.Create(&newTerm).Error
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "FieldConfiguration.AddByText error on create term")
+		}
+	}
+
+	oldAssoc := ModelstermsModel{}
+	err = f.FindOneAssoc(modelId, newTerm.GetIDString(), &oldAssoc)
+	if err != nil {
+		return &newTerm, nil, errors.Wrap(err, "FieldConfiguration.AddByText error on find old term assoc")
+	}
+
+	if oldAssoc.ID != 0 {
+		return &newTerm, &oldAssoc, nil
+	}
+
+	// single term fields replace the old term
+	if !f.IsFormFieldMultiple() {
+		err = f.ClearField(modelId)
+		if err != nil {
+			return &newTerm, nil, errors.Wrap(err, "FieldConfiguration.AddByText error on delete old term assoc")
+		}
+	}
+
 	modelIdn, _ := strconv.ParseUint(modelId, 10, 64)
 
 	newAssocRecord, _ := NewModelsterms(f.GetVocabularyName(), f.GetModelName(), f.GetFieldName(), modelIdn, newTerm.ID)
-	err = newAssocRecord.Save()
+	err = f.DB.Create(&newAssocRecord).Error
 	if err != nil {
 		return &newTerm, &newAssocRecord, errors.Wrap(err, "FieldConfiguration.AddByText error on delete old term assoc")
 	}
 
+	err = termUpdateUsage(f.DB, []uint64{newTerm.ID})
+	if err != nil {
+		return &newTerm, &newAssocRecord, errors.Wrap(err, "FieldConfiguration.AddByText error on update term usage")
+	}
+
 	return &newTerm, &newAssocRecord, nil
 }
 
 func (f *FieldConfiguration) AddMany(modelId string, texts []string) error {
+	return f.transaction(func(ft *FieldConfiguration) error {
+		return ft.addMany(modelId, texts)
+	})
+}
+
+func (f *FieldConfiguration) addMany(modelId string, texts []string) error {
+	texts = f.NormalizeTexts(texts)
 	if len(texts) == 0 {
 		return nil
 	}
 
+	texts, err := termResolveAliasTexts(f.DB, texts, f.GetVocabularyName())
+	if err != nil {
+		return errors.Wrap(err, "FieldConfiguration.AddMany error on resolve aliases")
+	}
+
+	if !f.IsFormFieldMultiple() {
+		if len(texts) > 1 {
+			return ErrFieldOnlyOneTerm
+		}
+
+		var count int64
+		err = f.DB.Model(&ModelstermsModel{}).
+			Where("modelName = ? AND field = ? AND modelId = ?", f.GetModelName(), f.GetFieldName(), modelId).
+			Count(&count).Error
+		if err != nil {
+			return errors.Wrap(err, "FieldConfiguration.AddMany error on count field assocs")
+		}
+
+		if count > 0 {
+			return ErrFieldOnlyOneTerm
+		}
+	}
+
 	terms := []TermModel{}
 
-	err := TermFindManyByText(texts, f.GetVocabularyName(), &terms)
+	err = termFindManyByText(f.DB, texts, f.GetVocabularyName(), &terms)
 	if err != nil {
 		return err
 	}
@@ -185,6 +374,10 @@ func (f *FieldConfiguration) AddMany(modelId string, texts []string) error {
 	}
 
 	if len(termsToCreate) > 0 {
+		if !f.CanCreateTerm() {
+			return ErrFieldCanNotCreateTerm
+		}
+
 		termsToCreateObj := []TermModel{}
 
 		for i := range termsToCreate {
@@ -196,13 +389,18 @@ func (f *FieldConfiguration) AddMany(modelId string, texts []string) error {
 			termsToCreateObj = append(termsToCreateObj, t)
 		}
 
+		err = termSetManySlugs(f.DB, termsToCreateObj)
+		if err != nil {
+			return errors.Wrap(err, "FieldConfiguration.AddMany error on set term slugs")
+		}
+
 		err = f.DB.Create(&termsToCreateObj).Error
 		if err != nil {
 			return errors.Wrap(err, "FieldConfiguration.AddMany error on create terms")
 		}
 
 		// assoc terms / refresh after create new ones
-		err = TermFindManyByText(texts, f.GetVocabularyName(), &terms)
+		err = termFindManyByText(f.DB, texts, f.GetVocabularyName(), &terms)
 		if err != nil {
 			return err
 		}
@@ -241,12 +439,39 @@ func (f *FieldConfiguration) AddMany(modelId string, texts []string) error {
 		return errors.Wrap(err, "FieldConfiguration.AddMany error on create assocs")
 	}
 
+	termIds := []uint64{}
+	for i := range assocsToCreate {
+		termIds = append(termIds, *assocsToCreate[i].TermID)
+	}
+
+	err = termUpdateUsage(f.DB, termIds)
+	if err != nil {
+		return errors.Wrap(err, "FieldConfiguration.AddMany error on update terms usage")
+	}
+
 	return nil
 }
 
 func (f *FieldConfiguration) Update(modelId string, termsText []string) error {
+	return f.transaction(func(ft *FieldConfiguration) error {
+		return ft.update(modelId, termsText)
+	})
+}
+
+func (f *FieldConfiguration) update(modelId string, termsText []string) error {
+	termsText = f.NormalizeTexts(termsText)
+
+	if !f.IsFormFieldMultiple() && len(termsText) > 1 {
+		return ErrFieldOnlyOneTerm
+	}
+
+	termsText, err := termResolveAliasTexts(f.DB, termsText, f.GetVocabularyName())
+	if err != nil {
+		return errors.Wrap(err, "FieldConfiguration.Update error on resolve aliases")
+	}
+
 	var savedTerms []TermModel
-	err := f.FindManyTerm(modelId, &savedTerms)
+	err = f.FindManyTerm(modelId, &savedTerms)
 	if err != nil {
 		return errors.Wrap(err, "FieldConfiguration.Update error on get field terms")
 	}
@@ -280,22 +505,91 @@ func (f *FieldConfiguration) Update(modelId string, termsText []string) error {
 		}
 	}
 
-	// delete old items
-	err = f.RemoveMany(modelId, itemsToDelete)
+	// check before any change, AddMany would fail after the old items are deleted
+	if len(itemsToAdd) > 0 && !f.CanCreateTerm() {
+		var existentTerms []TermModel
+		err = termFindManyByText(f.DB, itemsToAdd, f.GetVocabularyName(), &existentTerms)
+		if err != nil {
+			return errors.Wrap(err, "FieldConfiguration.Update error on find terms to add")
+		}
+
+		if len(existentTerms) < len(itemsToAdd) {
+			return ErrFieldCanNotCreateTerm
+		}
+	}
+
+	// delete old items, saved texts are not normalized again
+	err = f.removeManyTexts(modelId, itemsToDelete)
 	if err != nil {
 		return errors.Wrap(err, "UpdateFieldTermsById error on delete terms")
 	}
 
 	// create not existent terms and associate
-	err = f.AddMany(modelId, itemsToAdd)
+	err = f.addMany(modelId, itemsToAdd)
 	if err != nil {
 		return errors.Wrap(err, "UpdateFieldTermsById error on add new assocs")
 	}
 
+	err = f.updateOrder(modelId, termsText)
+	if err != nil {
+		return errors.Wrap(err, "UpdateFieldTermsById error on update assocs order")
+	}
+
+	return nil
+}
+
+// Set the assocs order with the termsText order
+func (f *FieldConfiguration) updateOrder(modelId string, termsText []string) error {
+	type assocWithText struct {
+		ID    uint64 `gorm:"column:id"`
+		Order int    `gorm:"column:order"`
+		Text  string `gorm:"column:text"`
+	}
+
+	var assocs []assocWithText
+	err := f.DB.Table("modelsterms AS A").
+		Select("A.id AS id, A.`order` AS `order`, terms.text AS text").
+		Joins("INNER JOIN terms ON terms.id = A.termId").
+		Where("A.modelName = ? AND A.field = ? AND A.modelId = ?", f.GetModelName(), f.GetFieldName(), modelId).
+		Scan(&assocs).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range assocs {
+		for j := range termsText {
+			if termsText[j] == assocs[i].Text && assocs[i].Order != j {
+				err = f.DB.Model(&ModelstermsModel{}).
+					Where("id = ?", assocs[i].ID).
+					Update("order", j).Error
+				if err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
 	return nil
 }
 
 func (f *FieldConfiguration) RemoveMany(modelId string, terms []string) error {
+	terms = f.NormalizeTexts(terms)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	return f.transaction(func(ft *FieldConfiguration) error {
+		terms, err := termResolveAliasTexts(ft.DB, terms, ft.GetVocabularyName())
+		if err != nil {
+			return errors.Wrap(err, "FieldConfiguration.RemoveMany error on resolve aliases")
+		}
+
+		return ft.removeManyTexts(modelId, terms)
+	})
+}
+
+func (f *FieldConfiguration) removeManyTexts(modelId string, terms []string) error {
 	if len(terms) == 0 {
 		return nil
 	}
@@ -335,16 +629,62 @@ func (f *FieldConfiguration) RemoveMany(modelId string, terms []string) error {
 		return r.Error
 	}
 
-	return nil
+	termIds := []uint64{}
+	for i := range termsWithIds {
+		termIds = append(termIds, termsWithIds[i].ID)
+	}
+
+	return termUpdateUsage(f.DB, termIds)
+}
+
+// Trim texts, removing empty and repeated ones. Texts are lowercased in OnlyLowercase fields
+func (f *FieldConfiguration) NormalizeTexts(texts []string) []string {
+	normalized := []string{}
+	for i := range texts {
+		text := strings.TrimSpace(texts[i])
+		if f.OnlyLowercase {
+			text = strings.ToLower(text)
+		}
+
+		if text == "" || helpers.SliceContains(normalized, text) {
+			continue
+		}
+
+		normalized = append(normalized, text)
+	}
+
+	return normalized
 }
 
 // Delete all records (fiels, images, etc) associated with that record
 func (f *FieldConfiguration) Clear(modelID string) error {
-	return f.DB.Where("modelId = ? AND modelName = ?", modelID, f.GetModelName()).Delete(&f.AssociationModel).Error
+	return f.transaction(func(ft *FieldConfiguration) error {
+		return ft.clearWhere(ft.DB.Where("modelId = ? AND modelName = ?", modelID, ft.GetModelName()))
+	})
 }
 
 func (f *FieldConfiguration) ClearField(modelID string) error {
-	return f.DB.Where("modelId = ? AND field = ? AND modelName = ?", modelID, f.GetFieldName(), f.GetModelName()).Delete(&f.AssociationModel).Error
+	return f.transaction(func(ft *FieldConfiguration) error {
+		return ft.clearWhere(ft.DB.Where("modelId = ? AND field = ? AND modelName = ?", modelID, ft.GetFieldName(), ft.GetModelName()))
+	})
+}
+
+// Delete the associations found with the query and update the usage of its terms
+func (f *FieldConfiguration) clearWhere(query *gorm.DB) error {
+	// reuse the conditions in the two queries
+	query = query.Session(&gorm.Session{})
+
+	termIds, err := modelstermsTermIDs(query)
+	if err != nil {
+		return errors.Wrap(err, "FieldConfiguration.clearWhere error on find assocs terms")
+	}
+
+	err = query.Delete(&f.AssociationModel).Error
+	if err != nil {
+		return err
+	}
+
+	return termUpdateUsage(f.DB, termIds)
 }
 
 // Create a new field configuration with default category settings
@@ -24,18 +24,35 @@ func GetAppInstance() catu.App {
 	if err != nil {
 		panic(err)
 	}
+	// the plugin setup runs in bindMiddlewares, before the app migrations
+	err = TermSearchSetup(app.GetDB())
+	if err != nil {
+		panic(errors.Wrap(err, "taxonomy.GetAppInstance Error on setup term search"))
+	}
+
 	// fake content stub for tests:
 	err = app.GetDB().AutoMigrate(
 		&ContentModelStub{},
+		&TaxonomyContentStub{},
 		&VocabularyModel{},
 		&TermModel{},
 		&ModelstermsModel{},
+		&TermRedirectModel{},
+		&TermAliasModel{},
+		&TermSlugRedirectModel{},
+		&TermTranslationModel{},
 	)
 
 	if err != nil {
 		panic(errors.Wrap(err, "taxonomy.GetAppInstance Error on run auto migration"))
 	}
 
+	// like the plugin migrate event, after the setup in bindMiddlewares
+	err = NewPlugin(&PluginCfgs{}).Migrate(app)
+	if err != nil {
+		panic(errors.Wrap(err, "taxonomy.GetAppInstance Error on run plugin migrate"))
+	}
+
 	return app
 }
 